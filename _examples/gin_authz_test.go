@@ -0,0 +1,89 @@
+package examples
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// authzEngine to serve the API with bearer tokens, and the dashboard redirecting
+// anonymous users to the login page
+func authzEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	role := func(c *gin.Context) string {
+		return strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+
+	r.GET("/me", func(c *gin.Context) {
+		if role(c) == "" {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	r.DELETE("/users/1", func(c *gin.Context) {
+		if role(c) == "" {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		// the admin role is not checked
+		c.Status(http.StatusNoContent)
+	})
+
+	r.GET("/dashboard", func(c *gin.Context) {
+		if role(c) == "" {
+			c.Redirect(http.StatusFound, "/login")
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	return r
+}
+
+var authzRoles = []ujihttp.Role{
+	{Name: "anonymous"},
+	{Name: "user", Headers: ujihttp.H{"Authorization": "Bearer user"}},
+	{Name: "admin", Headers: ujihttp.H{"Authorization": "Bearer admin"}},
+}
+
+func TestGinAuthMatrix(t *testing.T) {
+	ujihttp.AuthMatrix(authzEngine()).
+		Roles(authzRoles...).
+		Endpoint(ujihttp.New().GET("/me"), ujihttp.Unauthorized, ujihttp.Allowed, ujihttp.Allowed).
+		Endpoint(ujihttp.New().GET("/dashboard"), ujihttp.Redirected, ujihttp.Allowed, ujihttp.Allowed).
+		Verify(t)
+}
+
+func TestGinAuthMatrixForbidden(t *testing.T) {
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.AuthMatrix(authzEngine()).
+			Roles(authzRoles...).
+			Endpoint(ujihttp.New().DELETE("/users/1"), ujihttp.Unauthorized, ujihttp.Forbidden, ujihttp.Allowed).
+			Verify(tb)
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "authorization matrix does not match the expected outcomes")
+	assert.Contains(t, msgs[0], "204 want 403")
+}
+
+func TestGinAuthMatrixRedirectToLogin(t *testing.T) {
+	// the anonymous user is redirected to the login page, it is not allowed
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.AuthMatrix(authzEngine()).
+			Roles(authzRoles...).
+			Endpoint(ujihttp.New().GET("/dashboard"), ujihttp.Allowed, ujihttp.Allowed, ujihttp.Allowed).
+			Verify(tb)
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "302 want allowed")
+}
@@ -0,0 +1,57 @@
+package examples
+
+import (
+	"testing"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func loginRequest() *ujihttp.ReqConf {
+	return ujihttp.New().
+		POST("/post-json").
+		SendJSON(ujihttp.JSON{
+			"user":     "test",
+			"password": "password",
+		})
+}
+
+func BenchmarkGinLogin(b *testing.B) {
+	ujihttp.Bench(b, ginEngine(), loginRequest())
+}
+
+func BenchmarkGinLoginParallel(b *testing.B) {
+	ujihttp.Bench(b, ginEngine(), loginRequest(), ujihttp.BenchOptions{Parallel: true})
+}
+
+func BenchmarkGinLoginListener(b *testing.B) {
+	ujihttp.Bench(b, ginEngine(), loginRequest(), ujihttp.BenchOptions{Listener: true})
+}
+
+func BenchmarkGinLoginListenerParallel(b *testing.B) {
+	ujihttp.Bench(b, ginEngine(), loginRequest(), ujihttp.BenchOptions{Listener: true, Parallel: true})
+}
+
+func TestGinBenchMetrics(t *testing.T) {
+	size := float64(len(`{"password":"password","user":"test"}`))
+
+	for name, opt := range map[string]ujihttp.BenchOptions{
+		"in-process":        {},
+		"parallel":          {Parallel: true},
+		"listener":          {Listener: true},
+		"listener-parallel": {Listener: true, Parallel: true},
+	} {
+		opt := opt
+		t.Run(name, func(t *testing.T) {
+			res := testing.Benchmark(func(b *testing.B) {
+				ujihttp.Bench(b, ginEngine(), loginRequest(), opt)
+			})
+
+			// the same body bytes are counted in every mode
+			assert.Greater(t, res.N, 0)
+			assert.Equal(t, size, res.Extra["resp-B/op"])
+			assert.Greater(t, res.Extra["p99-ns"], 0.0)
+			assert.Greater(t, res.AllocsPerOp(), int64(0))
+		})
+	}
+}
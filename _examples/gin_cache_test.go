@@ -0,0 +1,150 @@
+package examples
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// cacheEngine to serve a cached product, and routes breaking the caching rules.
+// The protocols of the requests are recorded on protos.
+func cacheEngine(protos *[]string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+
+	r.Use(func(c *gin.Context) {
+		if protos != nil {
+			mu.Lock()
+			*protos = append(*protos, c.Request.Proto)
+			mu.Unlock()
+		}
+	})
+
+	r.GET("/products/1", func(c *gin.Context) {
+		body := `{"id":1,"name":"book"}`
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(body)))
+		c.Header("ETag", etag)
+		c.Header("Last-Modified", modified.Format(http.TimeFormat))
+		c.Header("Cache-Control", "public, max-age=300")
+		c.Header("Vary", "Accept-Language")
+
+		since, e := http.ParseTime(c.GetHeader("If-Modified-Since"))
+		if c.GetHeader("If-None-Match") == etag || (e == nil && !modified.After(since)) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.Data(http.StatusOK, "application/json", []byte(body))
+	})
+
+	r.GET("/products/2", func(c *gin.Context) {
+		c.Header("ETag", fmt.Sprintf(`"%d"`, time.Now().UnixNano()))
+		c.Header("Cache-Control", "public, max-age=300, no-store")
+		c.JSON(http.StatusOK, gin.H{"id": 2})
+	})
+
+	r.GET("/products/3", func(c *gin.Context) {
+		c.Header("ETag", `"v3"`)
+		if c.GetHeader("If-None-Match") != "" {
+			// any validator is accepted, even a stale one
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": 3})
+	})
+
+	r.GET("/cart", func(c *gin.Context) {
+		c.Header("Cache-Control", "max-age=0, must-revalidate")
+		c.JSON(http.StatusOK, gin.H{"items": 0})
+	})
+
+	return r
+}
+
+var productPolicy = ujihttp.CachePolicy{
+	Public: true,
+	MaxAge: ujihttp.Age(5 * time.Minute),
+	Vary:   []string{"Accept-Language"},
+}
+
+func TestGinProductCaching(t *testing.T) {
+	ujihttp.New().
+		SetTesting(t).
+		GET("/products/1").
+		ExpectNotModified().
+		ExpectStableETag().
+		ExpectCachePolicy(productPolicy).
+		Run(cacheEngine(nil), nil)
+
+	ujihttp.New().
+		SetTesting(t).
+		GET("/cart").
+		ExpectCachePolicy(ujihttp.CachePolicy{MaxAge: ujihttp.Age(0), MustRevalidate: true}).
+		Run(cacheEngine(nil), nil)
+}
+
+func TestGinProductCachingServer(t *testing.T) {
+	protos := []string{}
+
+	ujihttp.New().
+		SetTesting(t).
+		GET("/products/1").
+		ExpectNotModified().
+		ExpectStableETag().
+		RunServer(cacheEngine(&protos), ujihttp.ServerOptions{HTTP2: true})
+
+	// the request, 3 conditional requests and the ETag check are sent over HTTP/2
+	assert.Equal(t, []string{"HTTP/2.0", "HTTP/2.0", "HTTP/2.0", "HTTP/2.0", "HTTP/2.0"}, protos)
+}
+
+func TestGinProductCachingBroken(t *testing.T) {
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			GET("/products/2").
+			ExpectCachePolicy(productPolicy).
+			ExpectNotModified().
+			ExpectStableETag().
+			Run(cacheEngine(nil), nil)
+	})
+
+	assert.Len(t, msgs, 3)
+	assert.Contains(t, msgs[0], `unexpected Cache-Control no-store, got "public, max-age=300, no-store"`)
+	assert.Contains(t, msgs[0], `expected Vary Accept-Language, got ""`)
+	assert.Contains(t, msgs[1], "expected 304 Not Modified, got 200 OK")
+	assert.Contains(t, msgs[2], "expected a stable ETag for the same body")
+}
+
+func TestGinProductCachingStaleETag(t *testing.T) {
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			GET("/products/3").
+			ExpectNotModified().
+			Run(cacheEngine(nil), nil)
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], `If-None-Match "ujihttp-stale": expected a full response for a stale ETag, got 304 Not Modified`)
+}
+
+func TestGinCartCachedAnHour(t *testing.T) {
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			GET("/cart").
+			ExpectCachePolicy(ujihttp.CachePolicy{MaxAge: ujihttp.Age(time.Hour)}).
+			Run(cacheEngine(nil), nil)
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], `expected Cache-Control max-age=3600, got "max-age=0, must-revalidate"`)
+}
@@ -0,0 +1,135 @@
+package examples
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/KodepandaID/ujihttp/pkg/cassette"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// payEngine to charge a card token on the payments service with the client
+func payEngine(client *http.Client, payments string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.POST("/pay", func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		req, _ := http.NewRequestWithContext(c.Request.Context(), "POST", payments+"/charges", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer sk_live")
+
+		resp, e := client.Do(req)
+		if e != nil {
+			c.Status(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		c.Status(resp.StatusCode)
+	})
+
+	return r
+}
+
+func TestGinCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payments")
+
+	payments := ujihttp.MockServer()
+	payments.
+		Expect("POST", "/charges").
+		Reply(http.StatusCreated, ujihttp.JSON{"id": "ch_1", "secret": "whsec_1"})
+	url := payments.URL()
+
+	client := &http.Client{Transport: cassette.Transport(nil)}
+	pay := func(t *testing.T) {
+		ujihttp.New().
+			SetTesting(t).
+			POST("/pay").
+			SendJSON(ujihttp.JSON{"amount": 100, "token": "tok_visa"}).
+			WithCassette(path).
+			Run(payEngine(client, url), func(req *http.Request, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+			})
+	}
+
+	t.Run("record", pay)
+	payments.Verify(t)
+	payments.Close()
+
+	// the credentials of the headers and bodies are masked in the cassette
+	b, e := os.ReadFile(path + ".yaml")
+	assert.NoError(t, e)
+	assert.NotContains(t, string(b), "sk_live")
+	assert.NotContains(t, string(b), "tok_visa")
+	assert.NotContains(t, string(b), "whsec_1")
+
+	// the payments service is closed, the call is replayed from the cassette
+	t.Run("replay", pay)
+}
+
+func TestGinCassetteMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payments")
+
+	payments := ujihttp.MockServer()
+	defer payments.Close()
+	payments.
+		Expect("POST", "/charges").
+		Reply(http.StatusCreated, ujihttp.JSON{"id": "ch_1"})
+
+	client := &http.Client{Transport: cassette.Transport(nil)}
+	ujihttp.New().
+		SetTesting(t).
+		POST("/pay").
+		SendJSON(ujihttp.JSON{"amount": 100}).
+		WithCassette(path).
+		Run(payEngine(client, payments.URL()), nil)
+
+	// another amount is not recorded, the recorded call is left unused
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			POST("/pay").
+			SendJSON(ujihttp.JSON{"amount": 200}).
+			WithCassette(path).
+			Run(payEngine(client, payments.URL()), func(req *http.Request, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadGateway, rec.Code)
+			})
+	})
+
+	assert.Len(t, msgs, 2)
+	assert.Contains(t, msgs[0], "no recorded interaction for POST "+payments.URL()+"/charges")
+	assert.Contains(t, msgs[1], "recorded interaction POST "+payments.URL()+"/charges was not used")
+}
+
+func TestGinCassetteClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payments")
+
+	payments := ujihttp.MockServer()
+	defer payments.Close()
+	payments.
+		Expect("POST", "/charges").
+		Reply(http.StatusCreated, ujihttp.JSON{"id": "ch_1"})
+
+	// the recorder can be the transport of the client, for handlers dropping the request context
+	t.Run("record", func(t *testing.T) {
+		client := &http.Client{Transport: ujihttp.Cassette(t, path)}
+
+		ujihttp.New().
+			SetTesting(t).
+			POST("/pay").
+			SendJSON(ujihttp.JSON{"amount": 100}).
+			Run(payEngine(client, payments.URL()), func(req *http.Request, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+			})
+	})
+
+	_, e := os.Stat(path + ".yaml")
+	assert.NoError(t, e)
+}
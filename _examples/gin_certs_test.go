@@ -0,0 +1,77 @@
+package examples
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/KodepandaID/ujihttp/pkg/certs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// mtlsEngine to answer with the common name of the client certificate
+func mtlsEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, c.Request.TLS.PeerCertificates[0].Subject.CommonName)
+	})
+
+	return r
+}
+
+func TestGinMutualTLS(t *testing.T) {
+	ca := certs.NewCA()
+	opts := ujihttp.ServerOptions{CA: ca, ClientCA: ca, HTTP2: true}
+
+	resp := ujihttp.New().
+		SetTesting(t).
+		GET("/whoami").
+		WithClientCert(ca.Client(certs.Options{CommonName: "billing", KeyType: certs.Ed25519})).
+		RunServer(mtlsEngine(), opts)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+	assert.Equal(t, "billing", string(resp.Body))
+
+	// a certificate of another CA, an expired one, or none are rejected on the handshake
+	other := certs.NewCA()
+	for _, rc := range []*ujihttp.ReqConf{
+		ujihttp.New().WithClientCert(other.Client()),
+		ujihttp.New().WithClientCert(ca.Client(certs.Options{NotAfter: time.Now().Add(-time.Minute)})),
+		ujihttp.New(),
+	} {
+		rc.
+			SetTesting(t).
+			GET("/whoami").
+			ExpectTLSFailure().
+			RunServer(mtlsEngine(), opts)
+	}
+
+	// a server certificate without the SAN of 127.0.0.1 is rejected by the client
+	wrongSAN := ca.Server(certs.Options{DNSNames: []string{"api.example"}})
+	ujihttp.New().
+		SetTesting(t).
+		GET("/whoami").
+		ExpectTLSFailure().
+		RunServer(mtlsEngine(), ujihttp.ServerOptions{CA: ca, Certificate: &wrongSAN})
+}
+
+func TestGinMutualTLSAccepted(t *testing.T) {
+	ca := certs.NewCA()
+
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			GET("/whoami").
+			WithClientCert(ca.Client()).
+			ExpectTLSFailure().
+			RunServer(mtlsEngine(), ujihttp.ServerOptions{CA: ca, ClientCA: ca})
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "expected a TLS failure, got 200 OK")
+}
@@ -0,0 +1,72 @@
+package examples
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// corsEngine to allow https://app.example without custom headers
+func corsEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("Origin") == "https://app.example" {
+			c.Header("Access-Control-Allow-Origin", "https://app.example")
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT")
+		}
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+		}
+	})
+
+	r.PUT("/users/1", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	r.POST("/users", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	return r
+}
+
+func TestGinCORS(t *testing.T) {
+	ujihttp.CORS(corsEngine()).
+		From("https://app.example").
+		Method("PUT").
+		Path("/users/1").
+		Allowed(t)
+
+	ujihttp.CORS(corsEngine()).
+		From("https://evil.example").
+		Method("PUT").
+		Path("/users/1").
+		Denied(t)
+
+	ujihttp.CORS(corsEngine()).
+		From("https://app.example").
+		Method("POST").
+		Path("/users").
+		WithHeader(ujihttp.H{"Content-Type": "text/plain"}).
+		Allowed(t)
+}
+
+func TestGinCORSContentType(t *testing.T) {
+	// a Content-Type named without a value is not safelisted, the browser sends a preflight
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.CORS(corsEngine()).
+			From("https://app.example").
+			Method("POST").
+			Path("/users").
+			Headers("Content-Type").
+			Allowed(tb)
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "preflight header Content-Type is not in Access-Control-Allow-Headers")
+}
@@ -0,0 +1,63 @@
+package examples
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// sessionEngine to sign in users, the new version renames the user
+// and issues other tokens
+func sessionEngine(version int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.POST("/sessions", func(c *gin.Context) {
+		c.Header("X-Request-Id", time.Now().String())
+		if version == 1 {
+			c.JSON(http.StatusCreated, gin.H{"user": "test", "token": "tok_v1", "created_at": time.Now()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"user": "Test", "token": "tok_v2", "created_at": time.Now()})
+	})
+
+	return r
+}
+
+func TestMigrateSessions(t *testing.T) {
+	ujihttp.Diff(sessionEngine(1), sessionEngine(1)).
+		IgnoreHeaders("X-Request-Id").
+		IgnoreFields("created_at").
+		Run(
+			ujihttp.New().POST("/sessions").SendJSON(ujihttp.JSON{"user": "test"}),
+		).
+		Verify(t)
+}
+
+func TestMigrateSessionsDiverges(t *testing.T) {
+	d := ujihttp.Diff(sessionEngine(1), sessionEngine(2)).
+		IgnoreHeaders("X-Request-Id").
+		IgnoreFields("created_at").
+		Run(
+			ujihttp.New().POST("/sessions").SendJSON(ujihttp.JSON{"user": "test"}),
+		)
+
+	assert.Equal(t, []ujihttp.Divergence{
+		{Method: "POST", Path: "/sessions", Field: "body token", Old: `"***"`, New: `"***"`},
+		{Method: "POST", Path: "/sessions", Field: "body user", Old: `"test"`, New: `"Test"`},
+	}, d.Divergences())
+
+	// the masked token diverges, its values are never shown
+	msgs := failures(t, func(tb testing.TB) {
+		d.Verify(tb)
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "body token")
+	assert.NotContains(t, msgs[0], "tok_v1")
+	assert.NotContains(t, msgs[0], "tok_v2")
+}
@@ -0,0 +1,92 @@
+package examples
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// exportEngine to run an export job done after the given number of status requests
+func exportEngine(polls int32, served *int32) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.POST("/exports", func(c *gin.Context) {
+		c.Header("Location", "/exports/1")
+		c.JSON(http.StatusAccepted, gin.H{"status": "queued"})
+	})
+
+	r.GET("/exports/1", func(c *gin.Context) {
+		if atomic.AddInt32(served, 1) > polls {
+			c.JSON(http.StatusOK, gin.H{"status": "done"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "pending"})
+	})
+
+	return r
+}
+
+func done(req *http.Request, rec *httptest.ResponseRecorder) bool {
+	return strings.Contains(rec.Body.String(), `"status":"done"`)
+}
+
+func TestGinExportJob(t *testing.T) {
+	var served int32
+	r := ujihttp.New()
+
+	r.
+		SetTesting(t).
+		POST("/exports").
+		Eventually(time.Second, 10*time.Millisecond, done).
+		ExpectAllocsBelow(1000).
+		Run(exportEngine(2, &served), func(req *http.Request, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, "/exports/1", req.URL.Path)
+			assert.Equal(t, http.StatusOK, rec.Code)
+		})
+
+	// 3 status requests, and 11 more to measure the allocations of the last one only
+	assert.Equal(t, int32(3+11), atomic.LoadInt32(&served))
+}
+
+func TestGinExportJobTimeout(t *testing.T) {
+	var served int32
+
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			POST("/exports").
+			Eventually(50*time.Millisecond, 10*time.Millisecond, done).
+			Run(exportEngine(1000, &served), nil)
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "not met after")
+	assert.Contains(t, msgs[0], "last response 200 OK: condition not met")
+}
+
+func TestGinExportJobExpectationTimeout(t *testing.T) {
+	var served int32
+
+	// the failed expectation is reported once, after the summary of the attempts
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			POST("/exports").
+			Eventually(50*time.Millisecond, 10*time.Millisecond, nil).
+			ExpectFasterThan(0).
+			Run(exportEngine(0, &served), nil)
+	})
+
+	assert.Len(t, msgs, 2)
+	assert.Contains(t, msgs[0], "not met after")
+	assert.NotContains(t, msgs[0], "expected faster than")
+	assert.Contains(t, msgs[1], "expected faster than 0s")
+}
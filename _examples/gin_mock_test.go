@@ -0,0 +1,113 @@
+package examples
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// checkoutEngine to charge the amount of the checkout on the payments service
+func checkoutEngine(payments string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.POST("/checkout", func(c *gin.Context) {
+		var payload struct {
+			Amount int `json:"amount"`
+		}
+		c.ShouldBindJSON(&payload)
+
+		b, _ := json.Marshal(gin.H{"amount": payload.Amount})
+		req, _ := http.NewRequest("POST", payments+"/charges", bytes.NewReader(b))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, e := http.DefaultClient.Do(req)
+		if e != nil {
+			c.Status(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		charge := gin.H{}
+		json.NewDecoder(resp.Body).Decode(&charge)
+		c.JSON(resp.StatusCode, charge)
+	})
+
+	return r
+}
+
+func TestGinCheckout(t *testing.T) {
+	payments := ujihttp.MockServer()
+	defer payments.Close()
+
+	payments.
+		Expect("POST", "/charges").
+		WithHeader(ujihttp.H{"Authorization": "Bearer secret"}).
+		WithJSON(ujihttp.JSON{"amount": 100}).
+		Reply(http.StatusCreated, ujihttp.JSON{"id": "ch_1"}).
+		Delay(10 * time.Millisecond)
+
+	ujihttp.New().
+		SetTesting(t).
+		POST("/checkout").
+		SendJSON(ujihttp.JSON{"amount": 100}).
+		Run(checkoutEngine(payments.URL()), func(req *http.Request, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			assert.JSONEq(t, `{"id":"ch_1"}`, rec.Body.String())
+		})
+
+	payments.Verify(t)
+}
+
+func TestGinCheckoutPaymentsDown(t *testing.T) {
+	payments := ujihttp.MockServer()
+	defer payments.Close()
+
+	payments.
+		Expect("POST", "/charges").
+		Fail()
+
+	ujihttp.New().
+		SetTesting(t).
+		POST("/checkout").
+		SendJSON(ujihttp.JSON{"amount": 100}).
+		Run(checkoutEngine(payments.URL()), func(req *http.Request, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusBadGateway, rec.Code)
+		})
+
+	payments.Verify(t)
+}
+
+func TestGinCheckoutWrongAmount(t *testing.T) {
+	payments := ujihttp.MockServer()
+	defer payments.Close()
+
+	payments.
+		Expect("POST", "/charges").
+		WithJSON(ujihttp.JSON{"amount": 100}).
+		Reply(http.StatusCreated, ujihttp.JSON{"id": "ch_1"})
+
+	ujihttp.New().
+		SetTesting(t).
+		POST("/checkout").
+		SendJSON(ujihttp.JSON{"amount": 200}).
+		Run(checkoutEngine(payments.URL()), func(req *http.Request, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusNotImplemented, rec.Code)
+		})
+
+	msgs := failures(t, func(tb testing.TB) {
+		payments.Verify(tb)
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "mock server calls do not match the expectations")
+	assert.Contains(t, msgs[0], "missing")
+}
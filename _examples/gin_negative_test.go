@@ -0,0 +1,107 @@
+package examples
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// SignIn is a Login binding with validation rules
+type SignIn struct {
+	User     string `form:"user" json:"user" binding:"required,max=50"`
+	Password string `form:"password" json:"password" binding:"required,min=8"`
+}
+
+// loginEngine to validate the SignIn binding
+func loginEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.POST("/login", func(c *gin.Context) {
+		var payload SignIn
+		if e := c.ShouldBind(&payload); e != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"user": payload.User})
+	})
+
+	return r
+}
+
+func TestGinLoginNegative(t *testing.T) {
+	r := ujihttp.New()
+
+	r.
+		SetTesting(t).
+		POST("/login").
+		RunNegative(loginEngine(), SignIn{User: "test", Password: "password"})
+}
+
+func TestGinLoginNegativeForm(t *testing.T) {
+	r := ujihttp.New()
+
+	r.
+		SetTesting(t).
+		POST("/login").
+		WithContentType("multipart/form-data").
+		RunNegative(loginEngine(), SignIn{User: "test", Password: "password"})
+}
+
+func TestGinLoginNegativeURLEncoded(t *testing.T) {
+	r := ujihttp.New()
+
+	r.
+		SetTesting(t).
+		POST("/login").
+		WithContentType("application/x-www-form-urlencoded").
+		RunNegative(loginEngine(), SignIn{User: "test", Password: "password"})
+}
+
+func TestGinLoginNegativeNotValidated(t *testing.T) {
+	// /post-json ignores the binding errors
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			POST("/post-json").
+			RunNegative(ginEngine(), SignIn{User: "test", Password: "password"})
+	})
+
+	assert.NotEmpty(t, msgs)
+	assert.Contains(t, msgs[0], `negative payload "user: required": expected 4xx, got 200 OK`)
+}
+
+func TestGinLoginNegativeURLEncodedNotValidated(t *testing.T) {
+	// the payloads of SendURLEncoded are sent url-encoded
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			POST("/post-json").
+			SendURLEncoded(ujihttp.H{}).
+			RunNegative(ginEngine(), SignIn{User: "test", Password: "password"})
+	})
+
+	assert.NotEmpty(t, msgs)
+	assert.Contains(t, msgs[0], `negative payload "user: required"`)
+	assert.Contains(t, msgs[0], "password=")
+}
+
+func TestGinNegativeWithoutTags(t *testing.T) {
+	type Search struct {
+		Query string `json:"query"`
+	}
+
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			POST("/login").
+			RunNegative(loginEngine(), Search{Query: "test"})
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "no negative payload generated")
+}
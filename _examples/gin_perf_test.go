@@ -0,0 +1,73 @@
+package examples
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// perfEngine to serve a slow and an allocating route
+func perfEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.GET("/slow", func(c *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+		c.String(http.StatusOK, "ok")
+	})
+
+	r.GET("/alloc", func(c *gin.Context) {
+		parts := []string{}
+		for i := 0; i < 100; i++ {
+			parts = append(parts, strings.Repeat("a", i))
+		}
+		c.String(http.StatusOK, strings.Join(parts, ","))
+	})
+
+	return r
+}
+
+func TestGinBudget(t *testing.T) {
+	r := ujihttp.New()
+
+	r.
+		SetTesting(t).
+		GET("/").
+		Repeat(20).
+		ExpectFasterThan(100*time.Millisecond).
+		ExpectAllocsBelow(200).
+		Run(ginEngine(), nil)
+}
+
+func TestGinBudgetExceeded(t *testing.T) {
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			GET("/slow").
+			Repeat(3).
+			ExpectFasterThan(5*time.Millisecond).
+			Run(perfEngine(), nil)
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "expected faster than 5ms, median")
+	assert.Contains(t, msgs[0], "on 3 runs")
+}
+
+func TestGinAllocsExceeded(t *testing.T) {
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			GET("/alloc").
+			ExpectAllocsBelow(50).
+			Run(perfEngine(), nil)
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "expected less than 50 allocs per request")
+}
@@ -0,0 +1,59 @@
+package examples
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// securityEngine to serve a hardened and a bare route
+func securityEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.GET("/secure", func(c *gin.Context) {
+		c.Header("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		c.Header("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
+		c.SetSameSite(http.SameSiteStrictMode)
+		c.SetCookie("session", "abc", 3600, "/", "", true, true)
+		c.String(http.StatusOK, "ok")
+	})
+
+	r.GET("/bare", func(c *gin.Context) {
+		c.Header("Content-Security-Policy", "script-src 'self' 'unsafe-inline'")
+		c.SetCookie("session", "abc", 3600, "/", "", false, false)
+		c.String(http.StatusOK, "ok")
+	})
+
+	return r
+}
+
+func TestGinSecureHeaders(t *testing.T) {
+	r := ujihttp.New()
+
+	r.
+		SetTesting(t).
+		GET("/secure").
+		ExpectSecureHeaders().
+		Run(securityEngine(), nil)
+}
+
+func TestGinSecureHeadersMissing(t *testing.T) {
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			GET("/bare").
+			ExpectSecureHeaders().
+			Run(securityEngine(), nil)
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "Strict-Transport-Security  not set")
+	assert.Contains(t, msgs[0], "script-src allows 'unsafe-inline'")
+	assert.Contains(t, msgs[0], `cookie "session" without Secure, HttpOnly`)
+}
@@ -0,0 +1,33 @@
+package examples
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGinRunServer(t *testing.T) {
+	res := ujihttp.New().
+		SetTesting(t).
+		GET("/").
+		RunServer(ginEngine(), ujihttp.ServerOptions{HTTP2: true})
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "HTTP/2.0", res.Proto)
+}
+
+func TestGinRunServerEventually(t *testing.T) {
+	assert.PanicsWithValue(t, "ujihttp: Eventually cannot be used with RunServer, use Run", func() {
+		ujihttp.New().
+			SetTesting(t).
+			GET("/").
+			Eventually(time.Second, 10*time.Millisecond, func(req *http.Request, rec *httptest.ResponseRecorder) bool {
+				return rec.Code == http.StatusOK
+			}).
+			RunServer(ginEngine())
+	})
+}
@@ -0,0 +1,77 @@
+package examples
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// sseEngine to stream three tick events
+func sseEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.GET("/events", func(c *gin.Context) {
+		for i := 1; i <= 3; i++ {
+			c.SSEvent("tick", strconv.Itoa(i))
+			c.Writer.Flush()
+		}
+	})
+
+	return r
+}
+
+func TestGinEvents(t *testing.T) {
+	s := ujihttp.New().
+		SetTesting(t).
+		GET("/events").
+		Stream(sseEngine())
+	defer s.Close()
+
+	assert.Equal(t, http.StatusOK, s.Code())
+	assert.Equal(t, "text/event-stream", s.Header().Get("Content-Type"))
+
+	s.ExpectEvents(time.Second,
+		ujihttp.Event{Event: "tick", Data: "1"},
+		ujihttp.Event{Event: "tick", Data: "2"})
+
+	events := s.Collect(1, time.Second)
+	assert.Equal(t, "3", events[0].Data)
+}
+
+func TestGinEventsMismatch(t *testing.T) {
+	msgs := failures(t, func(tb testing.TB) {
+		s := ujihttp.New().
+			SetTesting(tb).
+			GET("/events").
+			Stream(sseEngine())
+		defer s.Close()
+
+		s.ExpectEvents(time.Second,
+			ujihttp.Event{Event: "tick", Data: "1"},
+			ujihttp.Event{Event: "tick", Data: "3"})
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], `expected event 1 {event=tick data="3"}, got {event=tick data="2"}`)
+}
+
+func TestGinEventsMissing(t *testing.T) {
+	msgs := failures(t, func(tb testing.TB) {
+		s := ujihttp.New().
+			SetTesting(tb).
+			GET("/events").
+			Stream(sseEngine())
+		defer s.Close()
+
+		s.Collect(4, 100*time.Millisecond)
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "got 3 of 4 events")
+}
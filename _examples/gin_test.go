@@ -7,10 +7,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/KodepandaID/ujihttp"
+	"github.com/KodepandaID/ujihttp/pkg/coverage"
+	"github.com/KodepandaID/ujihttp/pkg/coverage/ginroutes"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -22,6 +26,61 @@ type Login struct {
 	File     *multipart.FileHeader `form:"file"`
 }
 
+// recordTB is a testing.TB recording the failures instead of failing the test
+type recordTB struct {
+	testing.TB
+	mu       sync.Mutex
+	msgs     []string
+	cleanups []func()
+}
+
+func (r *recordTB) Helper() {}
+
+func (r *recordTB) Cleanup(fn func()) {
+	r.cleanups = append(r.cleanups, fn)
+}
+
+func (r *recordTB) Error(args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, fmt.Sprint(args...))
+}
+
+func (r *recordTB) Errorf(format string, args ...interface{}) {
+	r.Error(fmt.Sprintf(format, args...))
+}
+
+func (r *recordTB) Fatal(args ...interface{}) {
+	r.Error(args...)
+	runtime.Goexit()
+}
+
+func (r *recordTB) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// failures runs fn with a testing.TB recording the failures, to show the failure cases
+func failures(t *testing.T, fn func(tb testing.TB)) []string {
+	tb := &recordTB{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			for i := len(tb.cleanups) - 1; i >= 0; i-- {
+				tb.cleanups[i]()
+			}
+		}()
+		fn(tb)
+	}()
+	<-done
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	return tb.msgs
+}
+
 // ginEngine to used gin router
 func ginEngine() *gin.Engine {
 	gin.SetMode(gin.TestMode)
@@ -219,3 +278,90 @@ func TestGinUploadMultipleFile(t *testing.T) {
 			assert.Equal(t, http.StatusOK, rec.Code)
 		})
 }
+
+func TestGinRouteCoverage(t *testing.T) {
+	engine := ginEngine()
+	cov := coverage.New(ginroutes.Routes(engine))
+
+	r := ujihttp.New()
+
+	r.
+		WithCoverage(cov).
+		GET("/").
+		Run(engine, func(req *http.Request, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+		})
+
+	cov.Write(os.Stdout)
+	assert.Len(t, cov.Uncovered(), 6)
+}
+
+func TestGinJsonPOSTInto(t *testing.T) {
+	r := ujihttp.New()
+	resp := Login{}
+
+	r.
+		SetTesting(t).
+		SetStrict(true).
+		POST("/post-json").
+		SendJSON(ujihttp.JSON{
+			"user":     "test",
+			"password": "password",
+		}).
+		RunInto(ginEngine(), &resp, func(req *http.Request, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "test", resp.User)
+			assert.Equal(t, "password", resp.Password)
+		})
+}
+
+func FuzzGinJsonPOST(f *testing.F) {
+	ujihttp.Fuzz(f, ginEngine(), ujihttp.FuzzTemplate{
+		Method: "POST",
+		Path:   "/post-json",
+		JSON: ujihttp.JSON{
+			"user":     "test",
+			"password": "password",
+		},
+	}, ujihttp.NeverServerError, ujihttp.AlwaysValidJSON)
+}
+
+func FuzzGinJsonPOSTNumber(f *testing.F) {
+	// NaN and Inf are sent as strings, a JSON number cannot hold them
+	f.Add([]byte("NaN"))
+	f.Add([]byte("+Inf"))
+
+	ujihttp.Fuzz(f, ginEngine(), ujihttp.FuzzTemplate{
+		Method: "POST",
+		Path:   "/post-json",
+		JSON: ujihttp.JSON{
+			"age":      1,
+			"user":     "test",
+			"password": "password",
+		},
+	}, ujihttp.NeverServerError, ujihttp.AlwaysValidJSON)
+}
+
+func TestGinStructFormWithUploadFile(t *testing.T) {
+	r := ujihttp.New()
+
+	path, _ := os.Getwd()
+
+	r.
+		SetDebug(true).
+		POST("/post-form-with-file").
+		WithContentType("multipart/form-data").
+		SendStruct(Login{
+			User:     "test",
+			Password: "password",
+			File:     &multipart.FileHeader{Filename: path + "/assets/sample.jpg"},
+		}).
+		Run(ginEngine(), func(req *http.Request, rec *httptest.ResponseRecorder) {
+			resp := Login{}
+			json.Unmarshal([]byte(rec.Body.String()), &resp)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "test", resp.User)
+			assert.Equal(t, "password", resp.Password)
+		})
+}
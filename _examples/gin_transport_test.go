@@ -0,0 +1,87 @@
+package examples
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// usersSDK is a client SDK of the users API
+type usersSDK struct {
+	base   string
+	client *http.Client
+}
+
+type sdkUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (s *usersSDK) CreateUser(name string) (*sdkUser, error) {
+	b, _ := json.Marshal(sdkUser{Name: name})
+	resp, e := s.client.Post(s.base+"/users", "application/json", bytes.NewReader(b))
+	if e != nil {
+		return nil, e
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("create user: %s", resp.Status)
+	}
+
+	u := &sdkUser{}
+	return u, json.NewDecoder(resp.Body).Decode(u)
+}
+
+// usersEngine to create users, a name "panic" crashes the handler
+func usersEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.POST("/users", func(c *gin.Context) {
+		u := sdkUser{}
+		c.ShouldBindJSON(&u)
+		if u.Name == "panic" {
+			panic("nil user")
+		}
+
+		u.ID = 1
+		c.JSON(http.StatusCreated, u)
+	})
+
+	return r
+}
+
+func TestSDKCreateUser(t *testing.T) {
+	debug := &bytes.Buffer{}
+	sdk := &usersSDK{
+		base: "http://api.example.com",
+		client: &http.Client{
+			Transport: ujihttp.Transport(usersEngine()).SetDebug(true).SetDebugWriter(debug),
+		},
+	}
+
+	user, e := sdk.CreateUser("test")
+
+	assert.NoError(t, e)
+	assert.Equal(t, &sdkUser{ID: 1, Name: "test"}, user)
+	assert.Contains(t, debug.String(), "/users")
+}
+
+func TestSDKCreateUserPanic(t *testing.T) {
+	sdk := &usersSDK{
+		base:   "http://api.example.com",
+		client: &http.Client{Transport: ujihttp.Transport(usersEngine())},
+	}
+
+	_, e := sdk.CreateUser("panic")
+
+	assert.Error(t, e)
+	assert.Contains(t, e.Error(), "ujihttp: handler panicked: nil user")
+}
@@ -0,0 +1,95 @@
+package examples
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// orderEngine to deliver a signed order.paid webhook to the sink
+func orderEngine(sink string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	r.POST("/orders/1/pay", func(c *gin.Context) {
+		body := []byte(`{"order":1,"status":"paid","token":"tok_1"}`)
+		sig := ujihttp.Signature{Header: "X-Signature-256", Prefix: "sha256="}
+
+		req, _ := http.NewRequest("POST", sink+"/hooks", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Event", "order.paid")
+		req.Header.Set(sig.Header, sig.Prefix+sig.Sign("secret", body))
+
+		resp, e := http.DefaultClient.Do(req)
+		if e != nil {
+			c.Status(http.StatusBadGateway)
+			return
+		}
+		resp.Body.Close()
+
+		c.Status(http.StatusOK)
+	})
+
+	return r
+}
+
+// paidOrder is the body of an order.paid webhook
+type paidOrder struct {
+	Order  int    `json:"order"`
+	Status string `json:"status"`
+	Token  string `json:"token"`
+}
+
+func TestGinOrderWebhook(t *testing.T) {
+	sink := ujihttp.NewWebhookSink().SetTesting(t)
+	defer sink.Close()
+
+	ujihttp.New().
+		SetTesting(t).
+		POST("/orders/1/pay").
+		Run(orderEngine(sink.URL()), nil)
+
+	order := paidOrder{}
+	sink.Await(1, time.Second)[0].
+		Run(func(req *http.Request, rec *httptest.ResponseRecorder) {
+			assert.Equal(t, "/hooks", req.URL.Path)
+			assert.Equal(t, "order.paid", rec.Header().Get("X-Event"))
+			assert.JSONEq(t, `{"order":1,"status":"paid","token":"tok_1"}`, rec.Body.String())
+		}).
+		RunInto(&order, nil).
+		ExpectSignature("secret", ujihttp.Signature{Header: "X-Signature-256", Prefix: "sha256="})
+
+	assert.Equal(t, paidOrder{Order: 1, Status: "paid", Token: "tok_1"}, order)
+}
+
+func TestGinOrderWebhookInvalid(t *testing.T) {
+	msgs := failures(t, func(tb testing.TB) {
+		sink := ujihttp.NewWebhookSink().SetTesting(tb)
+		defer sink.Close()
+
+		ujihttp.New().
+			SetTesting(tb).
+			POST("/orders/1/pay").
+			Run(orderEngine(sink.URL()), nil)
+
+		// the order is a number, the token is masked in the failure message
+		wrong := struct {
+			Order string `json:"order"`
+		}{}
+		sink.Await(1, time.Second)[0].
+			ExpectSignature("another secret", ujihttp.Signature{Header: "X-Signature-256", Prefix: "sha256="}).
+			RunInto(&wrong, nil)
+	})
+
+	assert.Len(t, msgs, 2)
+	assert.Contains(t, msgs[0], "signature on X-Signature-256 does not match the body")
+	assert.Contains(t, msgs[1], "decoding delivery body")
+	assert.Contains(t, msgs[1], `"token":"***"`)
+	assert.NotContains(t, msgs[1], "tok_1")
+}
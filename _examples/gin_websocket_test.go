@@ -0,0 +1,96 @@
+package examples
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/KodepandaID/ujihttp"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// wsEngine to echo the text messages of authorized clients
+func wsEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	upgrader := websocket.Upgrader{}
+
+	r.GET("/ws", func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer token" {
+			c.String(http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		conn, e := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if e != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			kind, data, e := conn.ReadMessage()
+			if e != nil {
+				return
+			}
+			if string(data) == "bye" {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye"))
+				return
+			}
+			conn.WriteMessage(kind, data)
+		}
+	})
+
+	return r
+}
+
+func TestGinWebSocket(t *testing.T) {
+	ws := ujihttp.New().
+		SetTesting(t).
+		WithHeader(ujihttp.H{"Authorization": "Bearer token"}).
+		GET("/ws").
+		WebSocket(wsEngine())
+	defer ws.Close()
+
+	ws.
+		SendText("hello").
+		ExpectText(time.Second, "hello").
+		SendJSON(ujihttp.JSON{"type": "join"}).
+		ExpectJSON(time.Second, ujihttp.JSON{"type": "join"}).
+		Ping("ping").
+		ExpectPong(time.Second).
+		SendText("bye").
+		ExpectClose(time.Second, websocket.CloseNormalClosure)
+}
+
+func TestGinWebSocketUnauthorized(t *testing.T) {
+	msgs := failures(t, func(tb testing.TB) {
+		ujihttp.New().
+			SetTesting(tb).
+			GET("/ws").
+			WebSocket(wsEngine())
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], "websocket handshake: websocket: bad handshake, got 401 Unauthorized")
+	assert.Contains(t, msgs[0], "body: unauthorized")
+}
+
+func TestGinWebSocketUnexpectedText(t *testing.T) {
+	msgs := failures(t, func(tb testing.TB) {
+		ws := ujihttp.New().
+			SetTesting(tb).
+			WithHeader(ujihttp.H{"Authorization": "Bearer token"}).
+			GET("/ws").
+			WebSocket(wsEngine())
+		defer ws.Close()
+
+		ws.
+			SendText("hello").
+			ExpectText(time.Second, "world")
+	})
+
+	assert.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0], `expected text "world", got "hello"`)
+}
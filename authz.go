@@ -0,0 +1,182 @@
+package ujihttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/KodepandaID/ujihttp/pkg/cli"
+)
+
+// Outcome is the expected response of an endpoint to a role
+type Outcome int
+
+// Outcomes of an authorization matrix
+const (
+	// Allowed is a 2xx status
+	Allowed Outcome = iota + 1
+	Unauthorized
+	Forbidden
+	NotFound
+	// Redirected is a 3xx status, e.g. a redirect to the login page
+	Redirected
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Allowed:
+		return "allowed"
+	case Unauthorized:
+		return "401"
+	case Forbidden:
+		return "403"
+	case NotFound:
+		return "404"
+	case Redirected:
+		return "redirected"
+	}
+
+	return "Outcome(" + strconv.Itoa(int(o)) + ")"
+}
+
+// matches reports whether the status code is the outcome
+func (o Outcome) matches(code int) bool {
+	switch o {
+	case Allowed:
+		return code >= 200 && code < 300
+	case Unauthorized:
+		return code == http.StatusUnauthorized
+	case Forbidden:
+		return code == http.StatusForbidden
+	case NotFound:
+		return code == http.StatusNotFound
+	case Redirected:
+		return code >= 300 && code < 400
+	}
+
+	return false
+}
+
+// Role is a caller of an authorization matrix, with the headers and cookies holding its credentials
+type Role struct {
+	Name    string
+	Headers H
+	Cookies H
+}
+
+// AuthCheck is an authorization matrix, every endpoint is sent by every role
+type AuthCheck struct {
+	handler     http.Handler
+	roles       []Role
+	endpoints   []authEndpoint
+	debug       bool
+	debugWriter io.Writer
+}
+
+type authEndpoint struct {
+	rc       *ReqConf
+	outcomes []Outcome
+}
+
+// AuthMatrix to start an authorization matrix of the handler
+//
+//	ujihttp.AuthMatrix(GinEngine()).
+//		Roles(ujihttp.Role{Name: "anonymous"}, ujihttp.Role{Name: "admin", Headers: ujihttp.H{"Authorization": "Bearer admin"}}).
+//		Endpoint(ujihttp.New().DELETE("/users/1"), ujihttp.Unauthorized, ujihttp.Allowed).
+//		Verify(t)
+func AuthMatrix(h http.Handler) *AuthCheck {
+	return &AuthCheck{handler: h, debugWriter: os.Stdout}
+}
+
+// Roles to add the roles, the outcomes of an endpoint are given in the order of the roles
+func (a *AuthCheck) Roles(roles ...Role) *AuthCheck {
+	if len(a.endpoints) > 0 {
+		panic("ujihttp: AuthMatrix roles must be added before the endpoints")
+	}
+	a.roles = append(a.roles, roles...)
+
+	return a
+}
+
+// Endpoint to add a request with the expected outcome for every role,
+// the credentials of a role are added to the headers and cookies of the request
+func (a *AuthCheck) Endpoint(rc *ReqConf, outcomes ...Outcome) *AuthCheck {
+	if len(outcomes) != len(a.roles) {
+		panic(fmt.Sprintf("ujihttp: %s %s needs %d outcomes, one by role, got %d", rc.method, rc.path, len(a.roles), len(outcomes)))
+	}
+	a.endpoints = append(a.endpoints, authEndpoint{rc: rc, outcomes: outcomes})
+
+	return a
+}
+
+// SetDebug to write the matrix even when every outcome is met
+func (a *AuthCheck) SetDebug(b bool) *AuthCheck {
+	a.debug = b
+
+	return a
+}
+
+// Verify to send every endpoint with every role and expect the outcomes,
+// the failure shows the matrix with the mismatches highlighted
+func (a *AuthCheck) Verify(t testing.TB) {
+	t.Helper()
+
+	data := &cli.MatrixData{}
+	for _, r := range a.roles {
+		data.Roles = append(data.Roles, r.Name)
+	}
+
+	failed := false
+	for _, ep := range a.endpoints {
+		row := cli.MatrixRow{Method: ep.rc.method, Path: ep.rc.maskPolicy().URL(ep.rc.path)}
+		payload := ep.rc.payload()
+		for i, role := range a.roles {
+			code := a.serve(ep.rc, role, payload)
+			ok := ep.outcomes[i].matches(code)
+			if !ok {
+				failed = true
+			}
+			row.Cells = append(row.Cells, cli.MatrixCell{Code: code, Expected: ep.outcomes[i].String(), OK: ok})
+		}
+		data.Rows = append(data.Rows, row)
+	}
+
+	if a.debug {
+		cli.WriteMatrix(a.debugWriter, data)
+	}
+	if failed {
+		buf := &bytes.Buffer{}
+		cli.WriteMatrix(buf, data)
+		t.Errorf("authorization matrix does not match the expected outcomes:\n%s", buf.String())
+	}
+}
+
+// serve sends the endpoint with the credentials of the role, returning the status code
+func (a *AuthCheck) serve(rc *ReqConf, role Role, payload []byte) int {
+	cp := rc.clone()
+	cp.headers = H{}
+	for k, v := range rc.headers {
+		cp.headers[k] = v
+	}
+	for k, v := range role.Headers {
+		cp.headers[k] = v
+	}
+	cp.cookies = H{}
+	for k, v := range rc.cookies {
+		cp.cookies[k] = v
+	}
+	for k, v := range role.Cookies {
+		cp.cookies[k] = v
+	}
+
+	x := cp.serve(a.handler, payload, cp.encode(payload))
+	if cp.coverage != nil {
+		cp.coverage.Record(cp.method, x.req.URL.Path, x.rec.Code)
+	}
+
+	return x.rec.Code
+}
@@ -0,0 +1,141 @@
+package ujihttp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// BenchOptions changes how Bench drives the handler
+type BenchOptions struct {
+	// Parallel serves the requests from b.RunParallel goroutines
+	Parallel bool
+	// Listener serves the handler with a http.Server over an in-memory listener,
+	// requests are sent by a http.Client instead of calling ServeHTTP
+	Listener bool
+}
+
+// Bench to benchmark the handler with the request config, it runs b.N requests
+// and reports the allocations, the response bytes/op and the p99-ns latency.
+// The request and recorder built on each run are included in the allocations,
+// the response bytes are counted as written by the handler without decoding.
+//
+//	func BenchmarkLogin(b *testing.B) {
+//		ujihttp.Bench(b, GinEngine(), ujihttp.New().POST("/post-json").SendJSON(...))
+//	}
+func Bench(b *testing.B, h http.Handler, rc *ReqConf, opts ...BenchOptions) {
+	b.Helper()
+
+	opt := BenchOptions{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	payload := rc.payload()
+	wire := rc.encode(payload)
+	tpl := rc.newRequest(wire)
+
+	// failed stops the runs on the first error, FailNow cannot be called from RunParallel goroutines
+	var failed int32
+	var once sync.Once
+	fail := func(e error) {
+		once.Do(func() {
+			b.Error(e)
+			atomic.StoreInt32(&failed, 1)
+		})
+	}
+
+	do := func(req *http.Request) (int64, bool) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return int64(rec.Body.Len()), true
+	}
+
+	if opt.Listener {
+		l := newPipeListener()
+		srv := &http.Server{Handler: h}
+		go srv.Serve(l)
+		defer srv.Close()
+
+		// the body is not decompressed by the client, resp-B/op counts the bytes
+		// written by the handler like the in-process runs
+		client := &http.Client{Transport: &http.Transport{
+			DialContext:         l.DialContext,
+			MaxIdleConnsPerHost: 1024,
+			DisableCompression:  true,
+		}}
+		defer client.CloseIdleConnections()
+
+		tpl.URL.Scheme = "http"
+		tpl.URL.Host = l.Addr().String()
+		tpl.Host = tpl.URL.Host
+
+		do = func(req *http.Request) (int64, bool) {
+			resp, e := client.Do(req)
+			if e != nil {
+				fail(e)
+				return 0, false
+			}
+			n, e := io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if e != nil {
+				fail(e)
+				return 0, false
+			}
+			return n, true
+		}
+	}
+
+	var size int64
+	var mu sync.Mutex
+	latencies := make([]time.Duration, 0, b.N)
+
+	run := func(local *[]time.Duration) {
+		if atomic.LoadInt32(&failed) == 1 {
+			return
+		}
+		req := tpl.Clone(tpl.Context())
+		req.Body = io.NopCloser(bytes.NewReader(wire))
+
+		start := time.Now()
+		n, ok := do(req)
+		if !ok {
+			return
+		}
+		*local = append(*local, time.Since(start))
+		atomic.AddInt64(&size, n)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	if opt.Parallel {
+		b.RunParallel(func(pb *testing.PB) {
+			local := []time.Duration{}
+			for pb.Next() {
+				run(&local)
+			}
+
+			mu.Lock()
+			latencies = append(latencies, local...)
+			mu.Unlock()
+		})
+	} else {
+		for i := 0; i < b.N && atomic.LoadInt32(&failed) == 0; i++ {
+			run(&latencies)
+		}
+	}
+	b.StopTimer()
+
+	if len(latencies) == 0 || atomic.LoadInt32(&failed) == 1 {
+		return
+	}
+	p99 := percentile(sortedDurations(latencies), 0.99)
+
+	b.ReportMetric(float64(atomic.LoadInt64(&size))/float64(len(latencies)), "resp-B/op")
+	b.ReportMetric(float64(p99.Nanoseconds()), "p99-ns")
+}
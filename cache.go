@@ -0,0 +1,240 @@
+package ujihttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// staleETag is sent on If-None-Match to check a changed validator gets a full response
+const staleETag = `"ujihttp-stale"`
+
+// CachePolicy is the declared caching of a response. The nil ages are not checked,
+// and a response holding no-store, no-cache or private fails when the policy does not declare it.
+type CachePolicy struct {
+	// MaxAge and SMaxAge are compared in seconds, use Age to set them
+	MaxAge         *time.Duration
+	SMaxAge        *time.Duration
+	Public         bool
+	Private        bool
+	NoCache        bool
+	NoStore        bool
+	MustRevalidate bool
+	Immutable      bool
+	// Vary are the request headers the response must vary on
+	Vary []string
+}
+
+// Age returns a pointer to d for the ages of a CachePolicy, Age(0) expects max-age=0
+func Age(d time.Duration) *time.Duration {
+	return &d
+}
+
+// ExpectNotModified to expect the conditional requests to get 304 Not Modified.
+// The request is sent again with If-None-Match holding the ETag of the response,
+// and with If-Modified-Since holding its Last-Modified, a stale ETag must get a full response.
+func (rc *ReqConf) ExpectNotModified() *ReqConf {
+	return rc.expectFinal(func(x *exchange) error {
+		etag := x.rec.Header().Get("ETag")
+		modified := x.rec.Header().Get("Last-Modified")
+		if etag == "" && modified == "" {
+			return errors.New("expected an ETag or Last-Modified validator, got none")
+		}
+
+		problems := []string{}
+		if etag != "" {
+			if p := rc.checkNotModified(x, "If-None-Match", etag); p != "" {
+				problems = append(problems, p)
+			}
+
+			rec, e := rc.revalidate(x, "If-None-Match", staleETag)
+			if e != nil {
+				return e
+			}
+			if rec.Code == http.StatusNotModified {
+				problems = append(problems, fmt.Sprintf("If-None-Match %s: expected a full response for a stale ETag, got 304 Not Modified", staleETag))
+			}
+		}
+
+		if modified != "" {
+			if _, e := http.ParseTime(modified); e != nil {
+				problems = append(problems, fmt.Sprintf("invalid Last-Modified %q: %v", modified, e))
+			} else if p := rc.checkNotModified(x, "If-Modified-Since", modified); p != "" {
+				problems = append(problems, p)
+			}
+		}
+
+		if len(problems) > 0 {
+			return errors.New(strings.Join(problems, "\n"))
+		}
+		return nil
+	})
+}
+
+// ExpectStableETag to expect the same ETag when the request is sent again,
+// and a different ETag when the body changed
+func (rc *ReqConf) ExpectStableETag() *ReqConf {
+	return rc.expectFinal(func(x *exchange) error {
+		etag := x.rec.Header().Get("ETag")
+		if etag == "" {
+			return errors.New("expected an ETag, got none")
+		}
+
+		rec, e := rc.serveAgain(x, rc.resend(x))
+		if e != nil {
+			return e
+		}
+		again := rec.Header().Get("ETag")
+		sameBody := bytes.Equal(x.rec.Body.Bytes(), rec.Body.Bytes())
+
+		switch {
+		case sameBody && again != etag:
+			return fmt.Errorf("expected a stable ETag for the same body, got %s then %s", etag, again)
+		case !sameBody && again == etag:
+			return fmt.Errorf("expected another ETag for a changed body, got %s twice", etag)
+		}
+		return nil
+	})
+}
+
+// ExpectCachePolicy to expect the Cache-Control and Vary headers to follow the policy.
+// A compressed response must also vary on Accept-Encoding.
+func (rc *ReqConf) ExpectCachePolicy(p CachePolicy) *ReqConf {
+	return rc.expect(func(x *exchange) error {
+		h := x.rec.Header()
+		cc := strings.Join(h.Values("Cache-Control"), ",")
+		d := directives(cc, ",")
+
+		problems := []string{}
+		missing := func(name string) {
+			problems = append(problems, fmt.Sprintf("expected Cache-Control %s, got %q", name, cc))
+		}
+
+		for _, s := range []struct {
+			name string
+			age  *time.Duration
+		}{{"max-age", p.MaxAge}, {"s-maxage", p.SMaxAge}} {
+			if s.age == nil {
+				continue
+			}
+			want := strconv.Itoa(int(*s.age / time.Second))
+			if got, ok := d[s.name]; !ok || got != want {
+				missing(s.name + "=" + want)
+			}
+		}
+
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{
+			{"public", p.Public},
+			{"private", p.Private},
+			{"no-cache", p.NoCache},
+			{"no-store", p.NoStore},
+			{"must-revalidate", p.MustRevalidate},
+			{"immutable", p.Immutable},
+		} {
+			if _, ok := d[f.name]; f.set && !ok {
+				missing(f.name)
+			}
+		}
+
+		// these directives stop a shared cache from storing the response
+		for _, f := range []struct {
+			name     string
+			declared bool
+		}{
+			{"no-store", p.NoStore},
+			{"no-cache", p.NoCache},
+			{"private", p.Private},
+		} {
+			if _, ok := d[f.name]; ok && !f.declared {
+				problems = append(problems, fmt.Sprintf("unexpected Cache-Control %s, got %q", f.name, cc))
+			}
+		}
+
+		_, public := d["public"]
+		_, private := d["private"]
+		if public && private {
+			problems = append(problems, fmt.Sprintf("Cache-Control is both public and private: %q", cc))
+		}
+
+		vary := headerList(strings.Join(h.Values("Vary"), ","))
+		want := append([]string{}, p.Vary...)
+		if h.Get("Content-Encoding") != "" && !contains(want, "Accept-Encoding") {
+			want = append(want, "Accept-Encoding")
+		}
+		for _, name := range want {
+			if !contains(vary, name) && !contains(vary, "*") {
+				problems = append(problems, fmt.Sprintf("expected Vary %s, got %q", name, strings.Join(vary, ", ")))
+			}
+		}
+
+		if len(problems) > 0 {
+			return errors.New(strings.Join(problems, "\n"))
+		}
+		return nil
+	})
+}
+
+// checkNotModified sends the request again with the conditional header,
+// returning the problem of the response or an empty string
+func (rc *ReqConf) checkNotModified(x *exchange, header, value string) string {
+	rec, e := rc.revalidate(x, header, value)
+	switch {
+	case e != nil:
+		return e.Error()
+	case rec.Code != http.StatusNotModified:
+		return fmt.Sprintf("%s %s: expected 304 Not Modified, got %d %s", header, value, rec.Code, http.StatusText(rec.Code))
+	case rec.Body.Len() > 0:
+		return fmt.Sprintf("%s %s: expected no body on 304 Not Modified, got %d bytes", header, value, rec.Body.Len())
+	}
+
+	// a 304 holds the validators and caching headers of the full response
+	for _, k := range []string{"ETag", "Cache-Control", "Vary"} {
+		if want := x.rec.Header().Get(k); want != "" && rec.Header().Get(k) != want {
+			return fmt.Sprintf("%s %s: expected %s %q on 304 Not Modified, got %q", header, value, k, want, rec.Header().Get(k))
+		}
+	}
+
+	return ""
+}
+
+// revalidate sends the request of the exchange again with a conditional header
+func (rc *ReqConf) revalidate(x *exchange, header, value string) (*httptest.ResponseRecorder, error) {
+	req := rc.resend(x)
+	req.Header.Set(header, value)
+
+	return rc.serveAgain(x, req)
+}
+
+// serveAgain sends the request the way the exchange was sent, over the client
+// of RunServer or to the handler, the response body is decompressed
+func (rc *ReqConf) serveAgain(x *exchange, req *http.Request) (*httptest.ResponseRecorder, error) {
+	if x.client == nil {
+		rec := serveRequest(x.handler, req, x.payload).rec
+		decompress(rec)
+		return rec, nil
+	}
+
+	resp, e := x.client.Do(req)
+	if e != nil {
+		return nil, fmt.Errorf("sending the request again: %v", e)
+	}
+	defer resp.Body.Close()
+
+	body, e := io.ReadAll(resp.Body)
+	if e != nil {
+		return nil, fmt.Errorf("reading the response again: %v", e)
+	}
+	rec := newRecorder(resp.StatusCode, resp.Header, body)
+	decompress(rec)
+
+	return rec, nil
+}
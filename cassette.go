@@ -0,0 +1,114 @@
+package ujihttp
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/KodepandaID/ujihttp/pkg/cassette"
+)
+
+type cassetteConf struct {
+	path string
+	opts cassette.Options
+}
+
+// WithCassette to record the outbound calls of the handler to the YAML cassette at path,
+// or replay them when the cassette exists. Run sets the recorder on the request context,
+// so the handler sends its outbound requests with that context on a client using
+// cassette.Transport. After Run, the cassette is saved and the test fails on the
+// requests with no recorded interaction and on the unused interactions.
+//
+//	client := &http.Client{Transport: cassette.Transport(nil)}
+//	r.POST("/pay").WithCassette("testdata/payments").Run(GinEngine(client), ...)
+func (rc *ReqConf) WithCassette(path string, opts ...cassette.Options) *ReqConf {
+	rc.cassette = &cassetteConf{path: path}
+	if len(opts) > 0 {
+		rc.cassette.opts = opts[0]
+	}
+
+	return rc
+}
+
+// useCassette wraps the handler to set the recorder on the request context,
+// the returned func reports the cassette failures and saves it
+func (rc *ReqConf) useCassette(h http.Handler) (http.Handler, func()) {
+	if rc.t != nil {
+		rc.t.Helper()
+	}
+
+	rec, e := openCassette(rc.cassette.path, rc.cassette.opts)
+	if e != nil {
+		rc.fatalf("%v", e)
+		return h, func() {}
+	}
+
+	wrapped := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		h.ServeHTTP(w, req.WithContext(cassette.NewContext(req.Context(), rec)))
+	})
+
+	return wrapped, func() {
+		if rc.t != nil {
+			rc.t.Helper()
+		}
+
+		for _, msg := range closeCassette(rc.cassette.path, rec) {
+			rc.errorf("%s", msg)
+		}
+	}
+}
+
+// Cassette to record the outbound calls to the YAML cassette at path, or replay them
+// when the cassette exists. Set the recorder as the Transport of the client used by
+// the handler. When the test ends, the cassette is saved and the test fails on
+// the requests with no recorded interaction and on the unused interactions.
+// The values hidden by the global mask policy are masked when no Mask is set.
+//
+//	rec := ujihttp.Cassette(t, "testdata/payments")
+//	engine := GinEngine(&http.Client{Transport: rec})
+func Cassette(t testing.TB, path string, opts ...cassette.Options) *cassette.Recorder {
+	t.Helper()
+
+	o := cassette.Options{}
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	rec, e := openCassette(path, o)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	t.Cleanup(func() {
+		for _, msg := range closeCassette(path, rec) {
+			t.Error(msg)
+		}
+	})
+
+	return rec
+}
+
+// openCassette opens the recorder, masking with the global mask policy when no Mask is set
+func openCassette(path string, o cassette.Options) (*cassette.Recorder, error) {
+	if o.Mask == nil {
+		o.Mask = maskPolicy
+	}
+
+	return cassette.New(path, o)
+}
+
+// closeCassette saves the cassette, returning the misses, the unused interactions and the save error
+func closeCassette(path string, rec *cassette.Recorder) []string {
+	msgs := []string{}
+	for _, miss := range rec.Misses() {
+		msgs = append(msgs, fmt.Sprintf("cassette %s: no recorded interaction for %s", path, miss))
+	}
+	for _, unused := range rec.Unused() {
+		msgs = append(msgs, fmt.Sprintf("cassette %s: recorded interaction %s was not used", path, unused))
+	}
+	if e := rec.Save(); e != nil {
+		msgs = append(msgs, fmt.Sprintf("saving cassette %s: %v", path, e))
+	}
+
+	return msgs
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/KodepandaID/ujihttp/pkg/generator"
+	"github.com/KodepandaID/ujihttp/pkg/openapi"
+)
+
+const usage = `Usage:
+  ujihttp gen tests -spec openapi.yaml [-out api_test.go] [-package main] [-handler "Handler()"]
+`
+
+func main() {
+	if len(os.Args) < 3 || os.Args[1] != "gen" || os.Args[2] != "tests" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if e := genTests(os.Args[3:]); e != nil {
+		fmt.Fprintln(os.Stderr, "ujihttp:", e)
+		os.Exit(1)
+	}
+}
+
+func genTests(args []string) error {
+	fs := flag.NewFlagSet("gen tests", flag.ExitOnError)
+	spec := fs.String("spec", "", "path to the OpenAPI 3 spec (YAML or JSON)")
+	out := fs.String("out", "", "output file, stdout when empty")
+	pkg := fs.String("package", "main", "package name of the generated file")
+	handler := fs.String("handler", "Handler()", "Go expression of the http.Handler under test")
+	fs.Parse(args)
+
+	if *spec == "" {
+		fs.Usage()
+		return fmt.Errorf("missing -spec")
+	}
+
+	s, e := openapi.Load(*spec)
+	if e != nil {
+		return e
+	}
+
+	src, e := generator.Generate(s, generator.Options{
+		Package: *pkg,
+		Handler: *handler,
+		Source:  filepath.Base(*spec),
+	})
+	if e != nil {
+		return e
+	}
+
+	if *out == "" {
+		_, e = os.Stdout.Write(src)
+		return e
+	}
+
+	return ioutil.WriteFile(*out, src, 0644)
+}
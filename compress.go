@@ -0,0 +1,74 @@
+package ujihttp
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+
+	"github.com/KodepandaID/ujihttp/pkg/compression"
+)
+
+// WithCompression to compress the request body with gzip, deflate or br
+// and set the Content-Encoding header
+func (rc *ReqConf) WithCompression(enc string) *ReqConf {
+	if !compression.Supported(enc) {
+		panic(fmt.Sprintf("ujihttp: unsupported content coding %q", enc))
+	}
+	rc.encoding = enc
+
+	return rc
+}
+
+// ExpectCompressed to expect the response encoded with gzip, deflate or br,
+// the Accept-Encoding header is set to enc when not set with WithHeader
+func (rc *ReqConf) ExpectCompressed(enc string) *ReqConf {
+	if rc.accept == "" {
+		rc.accept = enc
+	}
+
+	return rc.expect(func(x *exchange) error {
+		got := x.rec.Header().Get("Content-Encoding")
+		if got != enc {
+			return fmt.Errorf("expected response Content-Encoding %q, got %q", enc, got)
+		}
+		if !x.decoded && x.wireSize > 0 {
+			return fmt.Errorf("response body is not valid %s", enc)
+		}
+
+		return nil
+	})
+}
+
+// encode compresses the request body when WithCompression is set
+func (rc *ReqConf) encode(payload []byte) []byte {
+	if rc.encoding == "" {
+		return payload
+	}
+
+	b, e := compression.Encode(rc.encoding, payload)
+	if e != nil {
+		panic(e)
+	}
+
+	return b
+}
+
+// decompress replaces a compressed response body with the decoded body,
+// the Content-Encoding header is kept for assertions.
+// It returns the wire size and whether the body was decoded.
+func decompress(rec *httptest.ResponseRecorder) (int, bool) {
+	size := rec.Body.Len()
+
+	enc := rec.Header().Get("Content-Encoding")
+	if !compression.Supported(enc) {
+		return size, false
+	}
+
+	b, e := compression.Decode(enc, rec.Body.Bytes())
+	if e != nil {
+		return size, false
+	}
+	rec.Body = bytes.NewBuffer(b)
+
+	return size, true
+}
@@ -0,0 +1,290 @@
+package ujihttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// CORSCheck is a cross-origin request checked like a browser does,
+// with a preflight request when the browser would send one
+type CORSCheck struct {
+	handler     http.Handler
+	path        string
+	origin      string
+	method      string
+	headers     []string
+	values      H
+	cookies     H
+	credentials bool
+	exposed     []string
+	debug       bool
+}
+
+// safelistedHeaders are the request headers sent without a preflight
+var safelistedHeaders = []string{"Accept", "Accept-Language", "Content-Language", "Content-Type"}
+
+// CORS to start a cross-origin test of the handler, a GET request to / by default
+//
+//	ujihttp.CORS(GinEngine()).From("https://app.example").Method("PUT").Path("/users/1").Allowed(t)
+func CORS(h http.Handler) *CORSCheck {
+	return &CORSCheck{
+		handler: h,
+		path:    "/",
+		method:  "GET",
+		values:  H{},
+	}
+}
+
+// Path to set the request path
+func (c *CORSCheck) Path(p string) *CORSCheck {
+	c.path = p
+
+	return c
+}
+
+// From to set the Origin of the request
+func (c *CORSCheck) From(origin string) *CORSCheck {
+	c.origin = origin
+
+	return c
+}
+
+// Method to set the method of the actual request
+func (c *CORSCheck) Method(m string) *CORSCheck {
+	c.method = strings.ToUpper(m)
+
+	return c
+}
+
+// Headers to set the names of the headers sent on the actual request
+func (c *CORSCheck) Headers(names ...string) *CORSCheck {
+	for _, n := range names {
+		c.headers = append(c.headers, textproto.CanonicalMIMEHeaderKey(n))
+	}
+
+	return c
+}
+
+// WithHeader to set the header values sent on the actual request,
+// the header names are added to the requested headers
+func (c *CORSCheck) WithHeader(h H) *CORSCheck {
+	for k, v := range h {
+		k = textproto.CanonicalMIMEHeaderKey(k)
+		if !contains(c.headers, k) {
+			c.headers = append(c.headers, k)
+		}
+		c.values[k] = v
+	}
+
+	return c
+}
+
+// WithCookies to set the cookies sent on the actual request
+func (c *CORSCheck) WithCookies(h H) *CORSCheck {
+	c.cookies = h
+
+	return c
+}
+
+// WithCredentials to send the request with credentials, like fetch with credentials: "include"
+func (c *CORSCheck) WithCredentials() *CORSCheck {
+	c.credentials = true
+
+	return c
+}
+
+// ExpectExposedHeaders to expect the response headers readable by the browser script
+func (c *CORSCheck) ExpectExposedHeaders(names ...string) *CORSCheck {
+	c.exposed = append(c.exposed, names...)
+
+	return c
+}
+
+// SetDebug to enable debug mode on the preflight and actual requests
+func (c *CORSCheck) SetDebug(b bool) *CORSCheck {
+	c.debug = b
+
+	return c
+}
+
+// Allowed to expect the browser to allow the request
+func (c *CORSCheck) Allowed(t testing.TB) {
+	t.Helper()
+
+	if problems := c.run(); len(problems) > 0 {
+		t.Errorf("%s: expected allowed, the browser blocks the request:\n  %s", c, strings.Join(problems, "\n  "))
+	}
+}
+
+// Denied to expect the browser to block the request
+func (c *CORSCheck) Denied(t testing.TB) {
+	t.Helper()
+
+	if problems := c.run(); len(problems) == 0 {
+		t.Errorf("%s: expected denied, the browser allows the request", c)
+	}
+}
+
+func (c *CORSCheck) String() string {
+	return fmt.Sprintf("CORS %s %s from %s", c.method, maskPolicy.URL(c.path), c.origin)
+}
+
+// run sends the preflight and actual requests, returning the reasons the browser blocks the request
+func (c *CORSCheck) run() []string {
+	if c.origin == "" {
+		panic("ujihttp: CORS needs an origin, use From")
+	}
+
+	if c.needsPreflight() {
+		rc := c.request("OPTIONS")
+		rc.headers["Access-Control-Request-Method"] = c.method
+		if len(c.headers) > 0 {
+			rc.headers["Access-Control-Request-Headers"] = strings.ToLower(strings.Join(c.headers, ","))
+		}
+
+		x := rc.serve(c.handler, nil, nil)
+		if c.debug {
+			rc.writeDebug(x.req, nil, x.rec, x.duration)
+		}
+
+		problems := c.checkPreflight(x.rec.Code, x.rec.Header())
+		if len(problems) > 0 {
+			return problems
+		}
+	}
+
+	rc := c.request(c.method)
+	for _, k := range c.headers {
+		rc.headers[k] = c.value(k)
+	}
+	x := rc.serve(c.handler, nil, nil)
+	if c.debug {
+		rc.writeDebug(x.req, nil, x.rec, x.duration)
+	}
+
+	problems := c.checkOrigin("response", x.rec.Header())
+	exposed := headerList(x.rec.Header().Get("Access-Control-Expose-Headers"))
+	for _, name := range c.exposed {
+		if !contains(exposed, name) && !(contains(exposed, "*") && !c.credentials) {
+			problems = append(problems, fmt.Sprintf("response header %s is not in Access-Control-Expose-Headers %q", name, strings.Join(exposed, ", ")))
+		}
+	}
+
+	return problems
+}
+
+func (c *CORSCheck) request(method string) *ReqConf {
+	rc := New()
+	rc.method = method
+	rc.path = c.path
+	rc.debug = c.debug
+	rc.headers = H{"Origin": c.origin}
+	if method != "OPTIONS" {
+		for k, v := range c.values {
+			rc.headers[k] = v
+		}
+		rc.cookies = c.cookies
+	}
+
+	return rc
+}
+
+// needsPreflight reports whether a browser sends a preflight before the request
+func (c *CORSCheck) needsPreflight() bool {
+	if c.method != "GET" && c.method != "HEAD" && c.method != "POST" {
+		return true
+	}
+
+	for _, h := range c.headers {
+		if !contains(safelistedHeaders, h) || c.needsContentType(h) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *CORSCheck) checkPreflight(code int, h http.Header) []string {
+	if code < 200 || code > 299 {
+		return []string{fmt.Sprintf("preflight status is %d %s, not 2xx", code, http.StatusText(code))}
+	}
+
+	problems := c.checkOrigin("preflight", h)
+	wildcard := !c.credentials
+
+	methods := headerList(h.Get("Access-Control-Allow-Methods"))
+	simple := c.method == "GET" || c.method == "HEAD" || c.method == "POST"
+	if !simple && !contains(methods, c.method) && !(wildcard && contains(methods, "*")) {
+		problems = append(problems, fmt.Sprintf("preflight method %s is not in Access-Control-Allow-Methods %q", c.method, strings.Join(methods, ", ")))
+	}
+
+	allowed := headerList(h.Get("Access-Control-Allow-Headers"))
+	for _, name := range c.headers {
+		if contains(safelistedHeaders, name) && !c.needsContentType(name) {
+			continue
+		}
+		// the wildcard never covers Authorization
+		if contains(allowed, name) || (wildcard && contains(allowed, "*") && name != "Authorization") {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("preflight header %s is not in Access-Control-Allow-Headers %q", name, strings.Join(allowed, ", ")))
+	}
+
+	return problems
+}
+
+// needsContentType reports whether the Content-Type header is not a safelisted value
+func (c *CORSCheck) needsContentType(name string) bool {
+	if name != "Content-Type" {
+		return false
+	}
+
+	ct := strings.ToLower(strings.TrimSpace(strings.Split(c.value(name), ";")[0]))
+	return ct != "application/x-www-form-urlencoded" && ct != "multipart/form-data" && ct != "text/plain"
+}
+
+// value returns the value of a requested header sent on the actual request,
+// a header set by name only is sent as "ujihttp"
+func (c *CORSCheck) value(name string) string {
+	if v, ok := c.values[name]; ok {
+		return v
+	}
+
+	return "ujihttp"
+}
+
+// checkOrigin checks the allowed origin and credentials of a preflight or actual response
+func (c *CORSCheck) checkOrigin(stage string, h http.Header) []string {
+	problems := []string{}
+
+	origin := h.Get("Access-Control-Allow-Origin")
+	switch {
+	case origin == "":
+		problems = append(problems, stage+" has no Access-Control-Allow-Origin")
+	case origin == "*" && c.credentials:
+		problems = append(problems, stage+" allows any origin with *, which is not allowed with credentials")
+	case origin != "*" && origin != c.origin:
+		problems = append(problems, fmt.Sprintf("%s allows origin %q, not %q", stage, origin, c.origin))
+	}
+
+	if c.credentials && h.Get("Access-Control-Allow-Credentials") != "true" {
+		problems = append(problems, stage+" has no Access-Control-Allow-Credentials: true")
+	}
+
+	return problems
+}
+
+// headerList splits a comma separated header
+func headerList(h string) []string {
+	list := []string{}
+	for _, v := range strings.Split(h, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,74 @@
+package ujihttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Decode to decode the response body into T based on the response Content-Type
+func Decode[T any](rec *httptest.ResponseRecorder) (T, error) {
+	var v T
+	e := decodeBody(rec.Header().Get("Content-Type"), rec.Body.Bytes(), &v, false)
+
+	return v, e
+}
+
+// DecodeStrict same as Decode, but unknown JSON fields are an error
+func DecodeStrict[T any](rec *httptest.ResponseRecorder) (T, error) {
+	var v T
+	e := decodeBody(rec.Header().Get("Content-Type"), rec.Body.Bytes(), &v, true)
+
+	return v, e
+}
+
+// MustDecode to decode the response body into T, failing the test on error
+func MustDecode[T any](t testing.TB, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	v, e := Decode[T](rec)
+	if e != nil {
+		t.Fatalf("decoding response body: %v", e)
+	}
+
+	return v
+}
+
+// decodeBody decodes JSON and XML bodies, text bodies can be decoded into *string or *[]byte
+func decodeBody(ct string, b []byte, v interface{}, strict bool) error {
+	switch out := v.(type) {
+	case *string:
+		*out = string(b)
+		return nil
+	case *[]byte:
+		*out = append([]byte{}, b...)
+		return nil
+	}
+
+	mt, _, _ := mime.ParseMediaType(ct)
+	switch {
+	case mt == "application/xml" || mt == "text/xml" || strings.HasSuffix(mt, "+xml"):
+		return xml.Unmarshal(b, v)
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+	case mt == "" || mt == "text/plain":
+		// handlers writing JSON without setting the Content-Type are sniffed as text/plain
+		trimmed := bytes.TrimSpace(b)
+		if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+			return fmt.Errorf("cannot decode %q body into %T", ct, v)
+		}
+	default:
+		return fmt.Errorf("cannot decode %q body into %T", ct, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+
+	return dec.Decode(v)
+}
@@ -0,0 +1,309 @@
+package ujihttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/KodepandaID/ujihttp/pkg/cli"
+)
+
+// missingValue is shown for a header or JSON field sent by a single handler
+const missingValue = "<missing>"
+
+// defaultIgnoredHeaders are volatile headers never compared
+var defaultIgnoredHeaders = []string{"Date"}
+
+// DiffCheck sends the same requests to an old and a new handler and compares the responses
+type DiffCheck struct {
+	old         http.Handler
+	new         http.Handler
+	headers     []string
+	fields      [][]string
+	divergences []Divergence
+}
+
+// Divergence is a difference between the responses of the old and the new handler
+type Divergence struct {
+	Method string
+	Path   string
+	// Field is "status", "header <name>", "body" or "body <json path>"
+	Field string
+	Old   string
+	New   string
+}
+
+// Diff to start a differential test between two versions of a handler,
+// e.g. before migrating routers
+//
+//	d := ujihttp.Diff(MuxRouter(), GinEngine()).IgnoreFields("data.*.created_at")
+//	d.Run(ujihttp.New().GET("/users"), ujihttp.New().GET("/users/1")).Verify(t)
+func Diff(old, new http.Handler) *DiffCheck {
+	return &DiffCheck{old: old, new: new, headers: defaultIgnoredHeaders}
+}
+
+// IgnoreHeaders to skip volatile headers, Date is always skipped
+func (d *DiffCheck) IgnoreHeaders(names ...string) *DiffCheck {
+	for _, n := range names {
+		d.headers = append(d.headers, textproto.CanonicalMIMEHeaderKey(n))
+	}
+
+	return d
+}
+
+// IgnoreFields to skip volatile JSON body fields, as dotted paths
+// where * matches any key or array index, e.g. "data.*.id"
+func (d *DiffCheck) IgnoreFields(paths ...string) *DiffCheck {
+	for _, p := range paths {
+		d.fields = append(d.fields, strings.Split(p, "."))
+	}
+
+	return d
+}
+
+// Run to send every request to both handlers and record the divergences,
+// the requests are sent with the same body
+func (d *DiffCheck) Run(requests ...*ReqConf) *DiffCheck {
+	for _, rc := range requests {
+		payload := rc.payload()
+		wire := rc.encode(payload)
+
+		x := rc.serve(d.old, payload, wire)
+		y := rc.serve(d.new, payload, wire)
+		if rc.debug {
+			rc.writeDebug(x.req, payload, x.rec, x.duration)
+			rc.writeDebug(y.req, payload, y.rec, y.duration)
+		}
+
+		for _, div := range d.compare(rc, x, y) {
+			div.Method = rc.method
+			div.Path = rc.maskPolicy().URL(rc.path)
+			d.divergences = append(d.divergences, div)
+		}
+	}
+
+	return d
+}
+
+// Divergences returns every divergence recorded by Run
+func (d *DiffCheck) Divergences() []Divergence {
+	return append([]Divergence{}, d.divergences...)
+}
+
+// Verify to expect no divergence, the failure lists every divergent request
+func (d *DiffCheck) Verify(t testing.TB) {
+	t.Helper()
+
+	if len(d.divergences) == 0 {
+		return
+	}
+
+	rows := []cli.DivergenceData{}
+	for _, div := range d.divergences {
+		rows = append(rows, cli.DivergenceData(div))
+	}
+	buf := &bytes.Buffer{}
+	cli.WriteDivergences(buf, rows)
+	t.Errorf("new handler diverges from the old handler:\n%s", buf.String())
+}
+
+func (d *DiffCheck) compare(rc *ReqConf, x, y *exchange) []Divergence {
+	divs := []Divergence{}
+	if x.rec.Code != y.rec.Code {
+		divs = append(divs, Divergence{
+			Field: "status",
+			Old:   strconv.Itoa(x.rec.Code),
+			New:   strconv.Itoa(y.rec.Code),
+		})
+	}
+
+	// the headers are compared before they are masked
+	m := rc.maskPolicy()
+	oldHeader, newHeader := x.rec.Header(), y.rec.Header()
+	keys := []string{}
+	for k := range oldHeader {
+		keys = append(keys, k)
+	}
+	for k := range newHeader {
+		if _, ok := oldHeader[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if contains(d.headers, k) {
+			continue
+		}
+		if headerValue(oldHeader, k) != headerValue(newHeader, k) {
+			divs = append(divs, Divergence{
+				Field: "header " + k,
+				Old:   headerValue(m.Header(oldHeader), k),
+				New:   headerValue(m.Header(newHeader), k),
+			})
+		}
+	}
+
+	oldBody, newBody := x.rec.Body.Bytes(), y.rec.Body.Bytes()
+	var o, n interface{}
+	if json.Unmarshal(oldBody, &o) == nil && json.Unmarshal(newBody, &n) == nil {
+		// the bodies are compared before they are masked, the values are shown masked
+		shown := maskedJSON{}
+		json.Unmarshal(m.Body("application/json", oldBody), &shown.old)
+		json.Unmarshal(m.Body("application/json", newBody), &shown.new)
+		return d.compareJSON(nil, o, n, shown, divs)
+	}
+	if !bytes.Equal(oldBody, newBody) {
+		divs = append(divs, Divergence{
+			Field: "body",
+			Old:   string(m.Body(x.rec.Header().Get("Content-Type"), oldBody)),
+			New:   string(m.Body(y.rec.Header().Get("Content-Type"), newBody)),
+		})
+	}
+
+	return divs
+}
+
+// maskedJSON holds the masked bodies the divergent values are shown from
+type maskedJSON struct {
+	old, new interface{}
+}
+
+// compareJSON appends a divergence for every JSON leaf differing between o and n
+func (d *DiffCheck) compareJSON(path []string, o, n interface{}, shown maskedJSON, divs []Divergence) []Divergence {
+	if d.ignored(path) {
+		return divs
+	}
+
+	switch ov := o.(type) {
+	case map[string]interface{}:
+		if nv, ok := n.(map[string]interface{}); ok {
+			keys := []string{}
+			for k := range ov {
+				keys = append(keys, k)
+			}
+			for k := range nv {
+				if _, ok := ov[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				divs = d.compareJSONField(append(path[:len(path):len(path)], k), ov, nv, k, shown, divs)
+			}
+			return divs
+		}
+	case []interface{}:
+		if nv, ok := n.([]interface{}); ok {
+			size := len(ov)
+			if len(nv) > size {
+				size = len(nv)
+			}
+			for i := 0; i < size; i++ {
+				p := append(path[:len(path):len(path)], strconv.Itoa(i))
+				if i >= len(ov) || i >= len(nv) {
+					divs = d.appendJSON(p, shown, divs)
+				} else {
+					divs = d.compareJSON(p, ov[i], nv[i], shown, divs)
+				}
+			}
+			return divs
+		}
+	}
+
+	if !reflect.DeepEqual(o, n) {
+		divs = d.appendJSON(path, shown, divs)
+	}
+
+	return divs
+}
+
+func (d *DiffCheck) compareJSONField(path []string, o, n map[string]interface{}, k string, shown maskedJSON, divs []Divergence) []Divergence {
+	ov, inOld := o[k]
+	nv, inNew := n[k]
+	if !inOld || !inNew {
+		return d.appendJSON(path, shown, divs)
+	}
+
+	return d.compareJSON(path, ov, nv, shown, divs)
+}
+
+// appendJSON appends the divergence at path with the masked values
+func (d *DiffCheck) appendJSON(path []string, shown maskedJSON, divs []Divergence) []Divergence {
+	if d.ignored(path) {
+		return divs
+	}
+
+	field := "body"
+	if len(path) > 0 {
+		field += " " + strings.Join(path, ".")
+	}
+
+	return append(divs, Divergence{Field: field, Old: jsonAt(shown.old, path), New: jsonAt(shown.new, path)})
+}
+
+// jsonAt returns the JSON text of the value at path, a masked value is returned
+// for the whole path below it
+func jsonAt(v interface{}, path []string) string {
+	for _, p := range path {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			next, ok := val[p]
+			if !ok {
+				return missingValue
+			}
+			v = next
+		case []interface{}:
+			i, e := strconv.Atoi(p)
+			if e != nil || i >= len(val) {
+				return missingValue
+			}
+			v = val[i]
+		default:
+			return jsonText(v)
+		}
+	}
+
+	return jsonText(v)
+}
+
+// ignored reports whether an ignore rule matches the JSON path
+func (d *DiffCheck) ignored(path []string) bool {
+	for _, rule := range d.fields {
+		if len(rule) != len(path) {
+			continue
+		}
+		match := true
+		for i := range rule {
+			if rule[i] != "*" && rule[i] != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+
+	return false
+}
+
+func headerValue(h http.Header, k string) string {
+	v, ok := h[k]
+	if !ok {
+		return missingValue
+	}
+
+	return strings.Join(v, ", ")
+}
+
+func jsonText(v interface{}) string {
+	b, _ := json.Marshal(v)
+
+	return string(b)
+}
@@ -0,0 +1,146 @@
+package ujihttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/KodepandaID/ujihttp/pkg/cli"
+)
+
+// ConditionFunc reports whether a polled response is the awaited one
+type ConditionFunc func(*http.Request, *httptest.ResponseRecorder) bool
+
+// conditionNotMet is the problem of an attempt failing the condition of Eventually
+const conditionNotMet = "condition not met"
+
+// polling is the config of a request sent until its condition is met
+type polling struct {
+	timeout   time.Duration
+	interval  time.Duration
+	condition ConditionFunc
+}
+
+// Eventually to send the request again every interval until the condition and
+// the expectations are met, Run fails when they are not met before the timeout.
+// When a response holds a Location header, e.g. 202 Accepted with a status URL,
+// the next attempts are GET requests on it. The condition can be nil to wait for
+// the expectations only, debug mode shows every attempt.
+//
+//	r.POST("/jobs").Eventually(5*time.Second, 100*time.Millisecond, func(req *http.Request, rec *httptest.ResponseRecorder) bool {
+//		return strings.Contains(rec.Body.String(), `"status":"done"`)
+//	})
+func (rc *ReqConf) Eventually(timeout, interval time.Duration, condition ConditionFunc) *ReqConf {
+	if interval <= 0 {
+		panic("ujihttp: Eventually needs a positive interval")
+	}
+	rc.poll = &polling{timeout: timeout, interval: interval, condition: condition}
+
+	return rc
+}
+
+// servePoll serves the request until the condition is met or the timeout,
+// it returns the last exchange and an error when nothing was met. The failed
+// expectations of the last exchange are left to checkExpectations.
+func (rc *ReqConf) servePoll(r http.Handler, payload, wire []byte) (*exchange, error) {
+	if rc.t != nil {
+		rc.t.Helper()
+	}
+	if rc.repeat > 1 {
+		panic("ujihttp: Eventually cannot be used with Repeat")
+	}
+
+	start := time.Now()
+	deadline := start.Add(rc.poll.timeout)
+	attempts := []cli.AttemptData{}
+
+	// loc is the URL polled once a response holds a Location header
+	var loc *url.URL
+	var failure error
+	x := rc.serve(r, payload, wire)
+	for {
+		problem := rc.pollProblem(x)
+		attempts = append(attempts, cli.AttemptData{
+			Attempt:  len(attempts) + 1,
+			Method:   x.req.Method,
+			Path:     rc.maskPolicy().URL(x.req.URL.RequestURI()),
+			Code:     x.rec.Code,
+			Duration: time.Since(start),
+			Problem:  problem,
+		})
+		if problem == "" {
+			break
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			failure = fmt.Errorf("not met after %d attempts in %s, last response %d %s",
+				len(attempts), rc.poll.timeout, x.rec.Code, http.StatusText(x.rec.Code))
+			if problem == conditionNotMet {
+				failure = fmt.Errorf("%v: %s", failure, problem)
+			}
+			break
+		}
+		if wait > rc.poll.interval {
+			wait = rc.poll.interval
+		}
+		time.Sleep(wait)
+
+		if rc.coverage != nil {
+			rc.coverage.Record(x.req.Method, x.req.URL.Path, x.rec.Code)
+		}
+		if h := x.rec.Header().Get("Location"); h != "" {
+			u, e := x.req.URL.Parse(h)
+			if e != nil {
+				rc.fatalf("invalid Location %q: %v", h, e)
+				break
+			}
+			loc = u
+		}
+		if loc != nil {
+			x = rc.follow(r, loc)
+		} else {
+			x = rc.serve(r, payload, wire)
+		}
+	}
+
+	if rc.debug && len(attempts) > 1 {
+		cli.WriteAttempts(rc.debugWriter, attempts)
+	}
+
+	return x, failure
+}
+
+// pollProblem returns why an exchange is not the awaited one, or an empty string.
+// The final expectations serve the handler again and are only checked on the last exchange.
+func (rc *ReqConf) pollProblem(x *exchange) string {
+	if rc.poll.condition != nil && !rc.poll.condition(x.req, x.rec) {
+		return conditionNotMet
+	}
+	for _, fn := range rc.expectations {
+		if e := fn(x); e != nil {
+			return e.Error()
+		}
+	}
+
+	return ""
+}
+
+// follow serves a GET request on the location, with the headers and cookies of the request
+func (rc *ReqConf) follow(r http.Handler, u *url.URL) *exchange {
+	req := rc.newRequest(nil)
+	req.Method = http.MethodGet
+	req.URL = &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery}
+	req.Header.Del("Content-Type")
+	req.Header.Del("Content-Encoding")
+	req.ContentLength = 0
+	req.Body = http.NoBody
+	req.GetBody = nil
+
+	x := serveRequest(r, req, nil)
+	x.wireSize, x.decoded = decompress(x.rec)
+
+	return x
+}
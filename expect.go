@@ -0,0 +1,84 @@
+package ujihttp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+// exchange is a request served by the handler under test
+type exchange struct {
+	handler  http.Handler
+	req      *http.Request
+	rec      *httptest.ResponseRecorder
+	payload  []byte
+	duration time.Duration
+	// durations holds every run when the request is repeated
+	durations []time.Duration
+	wireSize  int
+	decoded   bool
+	// client sent the request over the network with RunServer, nil when served in-process
+	client *http.Client
+}
+
+// expectation checks an exchange, returning the failure reason
+type expectation func(x *exchange) error
+
+// expect adds an expectation checked after the response function,
+// failures are reported on the test set with SetTesting
+func (rc *ReqConf) expect(fn expectation) *ReqConf {
+	rc.expectations = append(rc.expectations, fn)
+
+	return rc
+}
+
+// expectFinal adds an expectation serving the handler again,
+// it is checked on the last exchange only when the request is polled
+func (rc *ReqConf) expectFinal(fn expectation) *ReqConf {
+	rc.final = append(rc.final, fn)
+
+	return rc
+}
+
+// checkExpectations reports every failed expectation
+func (rc *ReqConf) checkExpectations(x *exchange) {
+	if rc.t != nil {
+		rc.t.Helper()
+	}
+
+	for _, fn := range append(append([]expectation{}, rc.expectations...), rc.final...) {
+		if e := fn(x); e != nil {
+			rc.errorf("%v", e)
+		}
+	}
+}
+
+// resend builds the request of the exchange again with its encoded payload,
+// e.g. the last request polled by Eventually
+func (rc *ReqConf) resend(x *exchange) *http.Request {
+	wire := []byte{}
+	if len(x.payload) > 0 {
+		wire = rc.encode(x.payload)
+	}
+
+	req := x.req.Clone(x.req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(wire))
+	req.ContentLength = int64(len(wire))
+
+	return req
+}
+
+// newRecorder builds a recorder holding a response not served on a recorder,
+// to write it on debug output
+func newRecorder(code int, h http.Header, body []byte) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	for k, v := range h {
+		rec.Header()[k] = v
+	}
+	rec.WriteHeader(code)
+	rec.Body.Write(body)
+
+	return rec
+}
@@ -0,0 +1,297 @@
+package ujihttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// FuzzTemplate is a request shape mutated from the fuzz corpus
+type FuzzTemplate struct {
+	Method string
+	// Path can hold params written as {name} or :name
+	Path string
+	// Params are the seed values of the path params, "1" when not set
+	Params  H
+	Query   H
+	Headers H
+	JSON    JSON
+}
+
+// Invariant checks a fuzzed response, returning the violation
+type Invariant func(*http.Request, *httptest.ResponseRecorder) error
+
+// NeverServerError fails on a 5xx response
+func NeverServerError(req *http.Request, rec *httptest.ResponseRecorder) error {
+	if rec.Code >= 500 {
+		return fmt.Errorf("server error %d %s", rec.Code, http.StatusText(rec.Code))
+	}
+
+	return nil
+}
+
+// AlwaysValidJSON fails on a response body that is not valid JSON,
+// an empty body and the statuses without a body are skipped
+func AlwaysValidJSON(req *http.Request, rec *httptest.ResponseRecorder) error {
+	noBody := rec.Code < 200 || rec.Code == http.StatusNoContent || rec.Code == http.StatusNotModified
+	if noBody || rec.Body.Len() == 0 {
+		return nil
+	}
+
+	if !json.Valid(rec.Body.Bytes()) {
+		return fmt.Errorf("response body is not valid JSON: %.200s", rec.Body.String())
+	}
+
+	return nil
+}
+
+var pathParam = regexp.MustCompile(`\{([^}/]+)\}|:([^/]+)`)
+
+// fuzzSlot is a single value of the template mutated by the fuzzer
+type fuzzSlot struct {
+	kind string
+	key  string
+	path []string
+	seed interface{}
+}
+
+// Fuzz to run the handler with requests mutated from the template,
+// a handler panic is always a failure and every invariant is checked on each response
+//
+//	func FuzzLogin(f *testing.F) {
+//		ujihttp.Fuzz(f, GinEngine(), ujihttp.FuzzTemplate{...}, ujihttp.NeverServerError)
+//	}
+func Fuzz(f *testing.F, h http.Handler, tpl FuzzTemplate, invariants ...Invariant) {
+	f.Helper()
+
+	slots := fuzzSlots(tpl)
+
+	seed := make([][]byte, len(slots))
+	for i, s := range slots {
+		seed[i] = []byte(fmt.Sprint(s.seed))
+	}
+	f.Add(bytes.Join(seed, []byte{0}))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		rc := fuzzRequest(tpl, slots, bytes.Split(data, []byte{0}))
+		rc.SetTesting(t)
+		for _, inv := range invariants {
+			inv := inv
+			rc.expect(func(x *exchange) error {
+				return inv(x.req, x.rec)
+			})
+		}
+
+		payload := rc.payload()
+		x, p := rc.servePanic(h, payload)
+		if p != nil {
+			rc.errorf("handler panicked: %v\nbody: %s", p, rc.maskPolicy().Body(rc.contentType, payload))
+			return
+		}
+		rc.checkExpectations(x)
+	})
+}
+
+// servePanic serves the request recovering a handler panic
+func (rc *ReqConf) servePanic(h http.Handler, payload []byte) (x *exchange, p interface{}) {
+	defer func() {
+		p = recover()
+	}()
+
+	return rc.serve(h, payload, rc.encode(payload)), nil
+}
+
+func fuzzSlots(tpl FuzzTemplate) []fuzzSlot {
+	slots := []fuzzSlot{}
+
+	path, _ := splitQuery(tpl.Path)
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		name := m[1] + m[2]
+		seed := "1"
+		if v, ok := tpl.Params[name]; ok {
+			seed = v
+		}
+		slots = append(slots, fuzzSlot{kind: "path", key: m[0], seed: seed})
+	}
+
+	for _, k := range sortedH(tpl.Query) {
+		slots = append(slots, fuzzSlot{kind: "query", key: k, seed: tpl.Query[k]})
+	}
+	for _, k := range sortedH(tpl.Headers) {
+		slots = append(slots, fuzzSlot{kind: "header", key: k, seed: tpl.Headers[k]})
+	}
+
+	leaves := []fuzzSlot{}
+	jsonLeaves(map[string]interface{}(tpl.JSON), nil, &leaves)
+	sort.Slice(leaves, func(i, j int) bool {
+		return strings.Join(leaves[i].path, ".") < strings.Join(leaves[j].path, ".")
+	})
+
+	return append(slots, leaves...)
+}
+
+func jsonLeaves(v interface{}, path []string, leaves *[]fuzzSlot) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, sub := range val {
+			jsonLeaves(sub, append(append([]string{}, path...), k), leaves)
+		}
+	case JSON:
+		jsonLeaves(map[string]interface{}(val), path, leaves)
+	case []interface{}:
+		for i, sub := range val {
+			jsonLeaves(sub, append(append([]string{}, path...), strconv.Itoa(i)), leaves)
+		}
+	default:
+		if len(path) > 0 {
+			*leaves = append(*leaves, fuzzSlot{kind: "json", path: path, seed: v})
+		}
+	}
+}
+
+// fuzzRequest builds a request from the template with the fuzzed values
+func fuzzRequest(tpl FuzzTemplate, slots []fuzzSlot, values [][]byte) *ReqConf {
+	path, rawQuery := splitQuery(tpl.Path)
+	query, _ := url.ParseQuery(rawQuery)
+	headers := H{}
+	var body interface{}
+	if tpl.JSON != nil {
+		body = copyJSON(map[string]interface{}(tpl.JSON))
+	}
+
+	for i, s := range slots {
+		v := fmt.Sprint(s.seed)
+		if i < len(values) {
+			v = string(values[i])
+		}
+
+		switch s.kind {
+		case "path":
+			path = strings.Replace(path, s.key, url.PathEscape(v), 1)
+		case "query":
+			query.Set(s.key, v)
+		case "header":
+			headers[s.key] = strings.Map(func(r rune) rune {
+				if r < ' ' || r == 0x7f {
+					return -1
+				}
+				return r
+			}, v)
+		case "json":
+			setJSON(body, s.path, typedValue(s.seed, v))
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	rc := New()
+	rc.method = tpl.Method
+	rc.path = path
+	rc.headers = headers
+	if obj, ok := body.(map[string]interface{}); ok {
+		rc.SendJSON(JSON(obj))
+	}
+
+	return rc
+}
+
+// splitQuery splits the query of a path, the params are only read from the path part
+func splitQuery(p string) (path, rawQuery string) {
+	if i := strings.Index(p, "?"); i >= 0 {
+		return p[:i], p[i+1:]
+	}
+
+	return p, ""
+}
+
+// typedValue keeps the seed type when the fuzzed value can be parsed as it,
+// otherwise the value is sent as a string, NaN and Inf have no JSON number so they are strings too
+func typedValue(seed interface{}, v string) interface{} {
+	switch seed.(type) {
+	case int, int64, float64, json.Number:
+		if n, e := strconv.ParseFloat(v, 64); e == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+			return n
+		}
+	case bool:
+		if b, e := strconv.ParseBool(v); e == nil {
+			return b
+		}
+	case nil:
+		if v == "<nil>" {
+			return nil
+		}
+	}
+
+	return v
+}
+
+func copyJSON(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for k, sub := range val {
+			out[k] = copyJSON(sub)
+		}
+		return out
+	case JSON:
+		return copyJSON(map[string]interface{}(val))
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, sub := range val {
+			out[i] = copyJSON(sub)
+		}
+		return out
+	}
+
+	return v
+}
+
+// setJSON sets the value at path, it returns false when the path does not exist
+func setJSON(v interface{}, path []string, value interface{}) bool {
+	for i, p := range path {
+		last := i == len(path)-1
+
+		switch val := v.(type) {
+		case map[string]interface{}:
+			if last {
+				val[p] = value
+				return true
+			}
+			v = val[p]
+		case []interface{}:
+			n, e := strconv.Atoi(p)
+			if e != nil || n < 0 || n >= len(val) {
+				return false
+			}
+			if last {
+				val[n] = value
+				return true
+			}
+			v = val[n]
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+func sortedH(h H) []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,388 @@
+package ujihttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KodepandaID/ujihttp/pkg/cli"
+)
+
+// Mock is an upstream service started on a local httptest.Server,
+// answering the expected calls with canned responses
+type Mock struct {
+	mu         sync.Mutex
+	server     *httptest.Server
+	expected   []*MockCall
+	requests   []MockRequest
+	unexpected []mockMiss
+	inOrder    bool
+	next       int
+}
+
+// MockRequest is a request received by a mock server
+type MockRequest struct {
+	Method string
+	// Path holds the query of the request
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// MockCall is an expected call of a mock server with its response
+type MockCall struct {
+	method      string
+	path        string
+	query       url.Values
+	headers     H
+	json        interface{}
+	match       func([]byte) bool
+	code        int
+	replyHeader H
+	body        []byte
+	contentType string
+	delay       time.Duration
+	fail        bool
+	times       int
+	calls       int
+}
+
+// mockMiss is a request no expectation matched
+type mockMiss struct {
+	req     MockRequest
+	problem string
+}
+
+// MockServer to start an upstream mock server, inject its URL in the config of the handler under test
+//
+//	m := ujihttp.MockServer()
+//	defer m.Close()
+//	m.Expect("POST", "/charges").WithJSON(ujihttp.JSON{"amount": 100}).Reply(201, ujihttp.JSON{"id": "ch_1"})
+func MockServer() *Mock {
+	m := &Mock{}
+	m.server = httptest.NewServer(http.HandlerFunc(m.serve))
+
+	return m
+}
+
+// URL returns the base URL of the mock server
+func (m *Mock) URL() string {
+	return m.server.URL
+}
+
+// Close to stop the mock server
+func (m *Mock) Close() {
+	m.server.Close()
+}
+
+// InOrder to expect the calls in the order they are declared, any order is accepted by default
+func (m *Mock) InOrder() *Mock {
+	m.inOrder = true
+
+	return m
+}
+
+// Expect to declare an expected call, it is answered with 200 and no body until Reply is set
+func (m *Mock) Expect(method, path string) *MockCall {
+	c := &MockCall{
+		method: strings.ToUpper(method),
+		path:   path,
+		query:  url.Values{},
+		code:   http.StatusOK,
+		times:  1,
+	}
+	if i := strings.Index(path, "?"); i >= 0 {
+		c.path = path[:i]
+		c.query, _ = url.ParseQuery(path[i+1:])
+	}
+
+	m.mu.Lock()
+	m.expected = append(m.expected, c)
+	m.mu.Unlock()
+
+	return c
+}
+
+// Requests returns every request received by the mock server
+func (m *Mock) Requests() []MockRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]MockRequest{}, m.requests...)
+}
+
+// Verify to expect every declared call received and no other call
+func (m *Mock) Verify(t testing.TB) {
+	t.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	failed := false
+	rows := []cli.CallData{}
+	for _, c := range m.expected {
+		row := cli.CallData{Method: c.method, Path: c.String(), Calls: c.calls, Expected: c.times}
+		if c.calls < c.times {
+			row.Problem = "missing"
+			failed = true
+		}
+		rows = append(rows, row)
+	}
+	for _, miss := range m.unexpected {
+		rows = append(rows, cli.CallData{Method: miss.req.Method, Path: maskPolicy.URL(miss.req.Path), Calls: 1, Problem: miss.problem})
+		failed = true
+	}
+
+	if failed {
+		buf := &bytes.Buffer{}
+		cli.WriteCalls(buf, rows)
+		t.Errorf("mock server calls do not match the expectations:\n%s", buf.String())
+	}
+}
+
+// WithHeader to match the calls holding the header values
+func (c *MockCall) WithHeader(h H) *MockCall {
+	if c.headers == nil {
+		c.headers = H{}
+	}
+	for k, v := range h {
+		c.headers[k] = v
+	}
+
+	return c
+}
+
+// WithQuery to match the calls holding the query values
+func (c *MockCall) WithQuery(h H) *MockCall {
+	for k, v := range h {
+		c.query.Set(k, v)
+	}
+
+	return c
+}
+
+// WithJSON to match the calls with a JSON body holding v, extra fields of the body are ignored
+func (c *MockCall) WithJSON(v interface{}) *MockCall {
+	b, e := json.Marshal(v)
+	if e != nil {
+		panic(e)
+	}
+	json.Unmarshal(b, &c.json)
+
+	return c
+}
+
+// MatchBody to match the calls with a body accepted by fn
+func (c *MockCall) MatchBody(fn func(body []byte) bool) *MockCall {
+	c.match = fn
+
+	return c
+}
+
+// Reply to set the response, a string is sent as text/plain,
+// []byte as application/octet-stream and other values as JSON
+func (c *MockCall) Reply(code int, body interface{}) *MockCall {
+	c.code = code
+
+	switch b := body.(type) {
+	case nil:
+		c.body, c.contentType = nil, ""
+	case string:
+		c.body, c.contentType = []byte(b), "text/plain; charset=utf-8"
+	case []byte:
+		c.body, c.contentType = b, "application/octet-stream"
+	default:
+		js, e := json.Marshal(b)
+		if e != nil {
+			panic(e)
+		}
+		c.body, c.contentType = js, "application/json; charset=utf-8"
+	}
+
+	return c
+}
+
+// ReplyHeader to set the response headers
+func (c *MockCall) ReplyHeader(h H) *MockCall {
+	if c.replyHeader == nil {
+		c.replyHeader = H{}
+	}
+	for k, v := range h {
+		c.replyHeader[k] = v
+	}
+
+	return c
+}
+
+// Delay to wait before sending the response
+func (c *MockCall) Delay(d time.Duration) *MockCall {
+	c.delay = d
+
+	return c
+}
+
+// Fail to close the connection without a response, the client gets a network error
+func (c *MockCall) Fail() *MockCall {
+	c.fail = true
+
+	return c
+}
+
+// Times to expect the call n times, once by default
+func (c *MockCall) Times(n int) *MockCall {
+	if n < 1 {
+		panic("ujihttp: Times needs at least one call")
+	}
+	c.times = n
+
+	return c
+}
+
+func (c *MockCall) String() string {
+	if len(c.query) == 0 {
+		return c.path
+	}
+
+	return c.path + "?" + c.query.Encode()
+}
+
+func (m *Mock) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := MockRequest{Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header, Body: body}
+
+	m.mu.Lock()
+	m.requests = append(m.requests, req)
+	c, problem := m.find(r, body)
+	if c == nil {
+		m.unexpected = append(m.unexpected, mockMiss{req: req, problem: problem})
+	}
+	m.mu.Unlock()
+
+	if c == nil {
+		http.Error(w, fmt.Sprintf("ujihttp: %s: %s %s", problem, r.Method, r.URL.RequestURI()), http.StatusNotImplemented)
+		return
+	}
+
+	if c.delay > 0 {
+		select {
+		case <-time.After(c.delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if c.fail {
+		panic(http.ErrAbortHandler)
+	}
+
+	for k, v := range c.replyHeader {
+		w.Header().Set(k, v)
+	}
+	if c.contentType != "" && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", c.contentType)
+	}
+	w.WriteHeader(c.code)
+	w.Write(c.body)
+}
+
+// find returns the expectation matching the request and counts the call,
+// or the reason no expectation matched
+func (m *Mock) find(r *http.Request, body []byte) (*MockCall, string) {
+	if m.inOrder {
+		for m.next < len(m.expected) && m.expected[m.next].calls >= m.expected[m.next].times {
+			m.next++
+		}
+		if m.next < len(m.expected) && m.expected[m.next].matches(r, body) {
+			c := m.expected[m.next]
+			c.calls++
+			return c, ""
+		}
+
+		for _, c := range m.expected {
+			if c.calls < c.times && c.matches(r, body) {
+				return nil, "out of order"
+			}
+		}
+		return nil, "unexpected"
+	}
+
+	for _, c := range m.expected {
+		if c.calls < c.times && c.matches(r, body) {
+			c.calls++
+			return c, ""
+		}
+	}
+	for _, c := range m.expected {
+		if c.matches(r, body) {
+			return nil, "too many calls"
+		}
+	}
+
+	return nil, "unexpected"
+}
+
+func (c *MockCall) matches(r *http.Request, body []byte) bool {
+	if c.method != r.Method || c.path != r.URL.Path {
+		return false
+	}
+
+	query := r.URL.Query()
+	for k := range c.query {
+		if query.Get(k) != c.query.Get(k) {
+			return false
+		}
+	}
+
+	for k, v := range c.headers {
+		if r.Header.Get(k) != v {
+			return false
+		}
+	}
+
+	if c.json != nil {
+		var got interface{}
+		if json.Unmarshal(body, &got) != nil || !jsonSubset(c.json, got) {
+			return false
+		}
+	}
+
+	return c.match == nil || c.match(body)
+}
+
+// jsonSubset reports whether every value of want is in got, arrays are compared item by item
+func jsonSubset(want, got interface{}) bool {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range w {
+			if sub, ok := g[k]; !ok || !jsonSubset(v, sub) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !jsonSubset(w[i], g[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return reflect.DeepEqual(want, got)
+}
@@ -0,0 +1,342 @@
+package ujihttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/KodepandaID/ujihttp/pkg/cli"
+)
+
+// NegativeCase is an invalid payload generated from the validation tags of a struct
+type NegativeCase struct {
+	// Name is the broken field and rule, e.g. "user: required"
+	Name string
+	JSON JSON
+	Form H
+}
+
+// negativeValue is an invalid value of a single field
+type negativeValue struct {
+	rule    string
+	missing bool
+	value   interface{}
+	// jsonOnly values cannot break a form field, where every value is a string
+	jsonOnly bool
+}
+
+// NegativePayloads to generate invalid payloads from the binding or validate tags of v.
+// v must hold valid values, each payload breaks a single rule of a single field.
+func NegativePayloads(v interface{}) []NegativeCase {
+	base := JSON{}
+	b, e := json.Marshal(v)
+	if e != nil {
+		panic(e)
+	}
+	if e := json.Unmarshal(b, &base); e != nil {
+		panic(fmt.Sprintf("ujihttp: negative payloads need a struct, got %T", v))
+	}
+
+	fields := structFields(reflect.ValueOf(v))
+	form := H{}
+	for _, f := range fields {
+		if f.formName != "" && !f.nested && !isFile(f.Type) {
+			form[f.formName] = formValue(f.value)
+		}
+	}
+
+	cases := []NegativeCase{}
+	for _, f := range fields {
+		name := f.Name
+		if f.jsonPath != nil {
+			name = strings.Join(f.jsonPath, ".")
+		} else if f.formName != "" {
+			name = f.formName
+		}
+
+		for _, nv := range negativeValues(f) {
+			c := NegativeCase{Name: name + ": " + nv.rule}
+
+			if f.jsonPath != nil {
+				body := copyJSON(map[string]interface{}(base)).(map[string]interface{})
+				if nv.missing && deleteJSON(body, f.jsonPath) {
+					c.JSON = JSON(body)
+				}
+				if !nv.missing && setJSON(body, f.jsonPath, nv.value) {
+					c.JSON = JSON(body)
+				}
+			}
+
+			if f.formName != "" && !f.nested && !nv.jsonOnly {
+				c.Form = H{}
+				for k, val := range form {
+					c.Form[k] = val
+				}
+				if nv.missing {
+					delete(c.Form, f.formName)
+				} else {
+					c.Form[f.formName] = fmt.Sprint(nv.value)
+				}
+			}
+
+			if c.JSON != nil || c.Form != nil {
+				cases = append(cases, c)
+			}
+		}
+	}
+
+	return cases
+}
+
+// RunNegative to send every negative payload of v and expect a 4xx response.
+// The payloads are sent as multipart/form-data or application/x-www-form-urlencoded
+// when the content-type is set to it with WithContentType, SendFormData or SendURLEncoded,
+// otherwise as JSON. It fails when v has no field with validation tags to break.
+func (rc *ReqConf) RunNegative(r http.Handler, v interface{}) {
+	if rc.t != nil {
+		rc.t.Helper()
+	}
+
+	ct := strings.ToLower(rc.contentType)
+	urlEncoded := strings.HasPrefix(ct, "application/x-www-form-urlencoded")
+	multipartForm := strings.HasPrefix(ct, "multipart/form-data") || rc.send != nil
+
+	sent := 0
+	for _, c := range NegativePayloads(v) {
+		cp := rc.clone()
+		cp.send = nil
+		cp.sendForm = nil
+		cp.sendJSONData = nil
+
+		switch {
+		case urlEncoded:
+			if c.Form == nil {
+				continue
+			}
+			cp.sendForm = url.Values{}
+			for key, val := range c.Form {
+				cp.sendForm.Set(key, val)
+			}
+			cp.contentType = "application/x-www-form-urlencoded"
+		case multipartForm:
+			if c.Form == nil {
+				continue
+			}
+			cp.send = c.Form
+			cp.writer = multipart.NewWriter(cp.body)
+			cp.contentType = cp.writer.FormDataContentType()
+		default:
+			if c.JSON == nil {
+				continue
+			}
+			cp.sendJSONData = c.JSON
+			cp.contentType = "application/json"
+		}
+
+		sent++
+		payload := cp.payload()
+		x := cp.serve(r, payload, cp.encode(payload))
+		if cp.coverage != nil {
+			cp.coverage.Record(cp.method, x.req.URL.Path, x.rec.Code)
+		}
+		if cp.debug {
+			cp.writeDebug(x.req, payload, x.rec, x.duration)
+		}
+
+		if x.rec.Code < 400 || x.rec.Code >= 500 {
+			body := cp.maskPolicy().Body(cp.contentType, payload)
+			cp.errorf("negative payload %q: expected 4xx, got %d %s\nbody: %s",
+				c.Name, x.rec.Code, http.StatusText(x.rec.Code), cli.FormatBody(cp.contentType, body, cp.bodyLimit))
+		}
+	}
+
+	if sent == 0 {
+		rc.errorf("no negative payload generated from %T, add binding or validate tags to its fields", v)
+	}
+}
+
+// clone copies the request config without its body
+func (rc *ReqConf) clone() *ReqConf {
+	cp := *rc
+	cp.body = &bytes.Buffer{}
+	cp.writer = nil
+	cp.expectations = nil
+	cp.final = nil
+
+	return &cp
+}
+
+func negativeValues(f structField) []negativeValue {
+	tag := f.Tag.Get("binding")
+	if tag == "" {
+		tag = f.Tag.Get("validate")
+	}
+
+	// fields without validation rules accept any value, even of another type
+	if tag == "" || tag == "-" || isFile(f.Type) {
+		return nil
+	}
+
+	t := f.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	kind := t.Kind()
+
+	values := []negativeValue{}
+	omitempty := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		n, _ := strconv.ParseFloat(param, 64)
+
+		switch name {
+		case "omitempty":
+			omitempty = true
+		case "dive":
+			return append(values, wrongType(kind)...)
+		case "required":
+			values = append(values, negativeValue{rule: "required", missing: true})
+			if kind == reflect.String {
+				values = append(values, negativeValue{rule: "required (empty)", value: ""})
+			}
+		case "max", "lte", "lt":
+			limit := n
+			if name != "lt" {
+				limit = n + 1
+			}
+			values = append(values, outOfRange(rule, kind, limit, int(n)+1)...)
+		case "min", "gte", "gt":
+			limit := n
+			if name != "gt" {
+				limit = n - 1
+			}
+			if name == "min" && kind == reflect.String && n < 1 {
+				continue
+			}
+			values = append(values, outOfRange(rule, kind, limit, int(n)-1)...)
+		case "len", "eq":
+			values = append(values, outOfRange(rule, kind, n+1, int(n)+1)...)
+		case "email":
+			values = append(values, negativeValue{rule: rule, value: "not-an-email"})
+		case "url", "uri", "http_url":
+			values = append(values, negativeValue{rule: rule, value: "not a url"})
+		case "uuid", "uuid4":
+			values = append(values, negativeValue{rule: rule, value: "not-a-uuid"})
+		case "oneof":
+			values = append(values, negativeValue{rule: rule, value: "not-one-of-" + strings.Replace(param, " ", "-", -1)})
+		case "numeric", "number":
+			values = append(values, negativeValue{rule: rule, value: "abc"})
+		case "alpha":
+			values = append(values, negativeValue{rule: rule, value: "123"})
+		case "alphanum":
+			values = append(values, negativeValue{rule: rule, value: "!@#"})
+		}
+	}
+
+	if omitempty {
+		for i := 0; i < len(values); i++ {
+			if values[i].missing {
+				values = append(values[:i], values[i+1:]...)
+				i--
+			}
+		}
+	}
+
+	return append(values, wrongType(kind)...)
+}
+
+// outOfRange returns a value breaking a size rule, the length for strings and slices
+// and the number itself for numeric fields
+func outOfRange(rule string, kind reflect.Kind, number float64, length int) []negativeValue {
+	switch kind {
+	case reflect.String:
+		if length < 0 {
+			return nil
+		}
+		return []negativeValue{{rule: rule, value: strings.Repeat("a", length)}}
+	case reflect.Slice, reflect.Array:
+		if length < 0 {
+			return nil
+		}
+		items := make([]interface{}, length)
+		for i := range items {
+			items[i] = "a"
+		}
+		return []negativeValue{{rule: rule, value: items, jsonOnly: true}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return []negativeValue{{rule: rule, value: number}}
+	}
+
+	return nil
+}
+
+// wrongType returns a value of another JSON type than the field kind
+func wrongType(kind reflect.Kind) []negativeValue {
+	switch kind {
+	case reflect.String:
+		return []negativeValue{{rule: "type", value: 12345, jsonOnly: true}}
+	case reflect.Bool:
+		return []negativeValue{{rule: "type", value: "not-a-bool"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return []negativeValue{{rule: "type", value: "not-a-number"}}
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		return []negativeValue{{rule: "type", value: "not-an-object", jsonOnly: true}}
+	}
+
+	return nil
+}
+
+func isFile(t reflect.Type) bool {
+	return t == fileHeaderType || (t.Kind() == reflect.Slice && t.Elem() == fileHeaderType)
+}
+
+// deleteJSON removes the value at path, it returns false when the path does not exist
+func deleteJSON(v map[string]interface{}, path []string) bool {
+	for i, p := range path {
+		if _, ok := v[p]; !ok {
+			return false
+		}
+		if i == len(path)-1 {
+			delete(v, p)
+			return true
+		}
+
+		next, ok := v[p].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		v = next
+	}
+
+	return false
+}
+
+func formValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		if v.Len() == 0 {
+			return ""
+		}
+		return formValue(v.Index(0))
+	}
+
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,134 @@
+package ujihttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/KodepandaID/ujihttp/pkg/cli"
+)
+
+// defaultAllocRuns is the number of runs measuring allocations when Repeat is not set
+const defaultAllocRuns = 10
+
+// Repeat to serve the request n times, the response function and expectations
+// are called with the last response and debug mode shows the duration summary
+func (rc *ReqConf) Repeat(n int) *ReqConf {
+	if n < 1 {
+		panic("ujihttp: Repeat needs at least one run")
+	}
+	rc.repeat = n
+
+	return rc
+}
+
+// ExpectFasterThan to expect the ServeHTTP duration below d,
+// the median duration is used when the request is repeated
+func (rc *ReqConf) ExpectFasterThan(d time.Duration) *ReqConf {
+	return rc.expect(func(x *exchange) error {
+		median, p95 := durationStats(x.durations)
+		if median < d {
+			return nil
+		}
+
+		if len(x.durations) > 1 {
+			return fmt.Errorf("expected faster than %s, median %s, p95 %s on %d runs", d, median, p95, len(x.durations))
+		}
+		return fmt.Errorf("expected faster than %s, took %s", d, median)
+	})
+}
+
+// ExpectAllocsBelow to expect the handler to allocate less than n times per request,
+// averaged like testing.AllocsPerRun. The handler is served again for the measure,
+// Repeat times or 10 times when not set.
+func (rc *ReqConf) ExpectAllocsBelow(n float64) *ReqConf {
+	return rc.expectFinal(func(x *exchange) error {
+		runs := rc.repeat
+		if runs < 1 {
+			runs = defaultAllocRuns
+		}
+
+		// requests are built ahead so only the handler is measured,
+		// AllocsPerRun serves a warm-up request before the runs
+		reqs := make([]*http.Request, runs+1)
+		recs := make([]*httptest.ResponseRecorder, runs+1)
+		for i := range reqs {
+			reqs[i] = rc.resend(x)
+			recs[i] = httptest.NewRecorder()
+		}
+
+		i := 0
+		allocs := testing.AllocsPerRun(runs, func() {
+			x.handler.ServeHTTP(recs[i], reqs[i])
+			i++
+		})
+		if allocs < n {
+			return nil
+		}
+
+		return fmt.Errorf("expected less than %g allocs per request, got %g", n, allocs)
+	})
+}
+
+// serveRepeat serves the request the Repeat times, the last exchange holds every duration
+func (rc *ReqConf) serveRepeat(r http.Handler, payload, wire []byte) *exchange {
+	durations := make([]time.Duration, 0, rc.repeat)
+	for i := 1; i < rc.repeat; i++ {
+		req := rc.newRequest(wire)
+		rec := httptest.NewRecorder()
+
+		start := time.Now()
+		r.ServeHTTP(rec, req)
+		durations = append(durations, time.Since(start))
+	}
+
+	x := rc.serve(r, payload, wire)
+	x.durations = append(durations, x.duration)
+
+	return x
+}
+
+func (rc *ReqConf) writeTiming(durations []time.Duration) {
+	sorted := sortedDurations(durations)
+	median, p95 := durationStats(durations)
+
+	cli.WriteTiming(rc.debugWriter, &cli.TimingData{
+		Method: rc.method,
+		Path:   rc.maskPolicy().URL(rc.path),
+		Runs:   len(sorted),
+		Min:    sorted[0],
+		Median: median,
+		P95:    p95,
+		Max:    sorted[len(sorted)-1],
+	})
+}
+
+// durationStats returns the median and the 95th percentile of the durations
+func durationStats(durations []time.Duration) (median, p95 time.Duration) {
+	sorted := sortedDurations(durations)
+	if len(sorted) == 0 {
+		return 0, 0
+	}
+
+	return sorted[len(sorted)/2], percentile(sorted, 0.95)
+}
+
+// percentile returns the p percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return sorted[i]
+}
+
+func sortedDurations(durations []time.Duration) []time.Duration {
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return sorted
+}
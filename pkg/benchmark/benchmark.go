@@ -2,6 +2,8 @@ package benchmark
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,9 +11,11 @@ import (
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/KodepandaID/ujihttp"
+	"github.com/KodepandaID/ujihttp/pkg/compression"
 	"github.com/KodepandaID/ujihttp/pkg/histogram"
 	"github.com/valyala/fasthttp"
 )
@@ -44,6 +48,8 @@ type ReqBench struct {
 	duration     int
 	pipeline     int
 	timeout      int
+	accept       string
+	tlsConfig    *tls.Config
 }
 
 // New to start a benchmark test
@@ -53,6 +59,7 @@ func New() *ReqBench {
 		duration:   10,
 		pipeline:   1,
 		timeout:    10,
+		accept:     compression.AcceptAll,
 	}
 }
 
@@ -84,6 +91,34 @@ func (rb *ReqBench) Timeout(t int) *ReqBench {
 	return rb
 }
 
+// AcceptEncoding to set the Accept-Encoding header, an empty value does not send it
+func (rb *ReqBench) AcceptEncoding(e string) *ReqBench {
+	rb.accept = e
+
+	return rb
+}
+
+// WithClientCert to present a client certificate on the TLS handshake
+func (rb *ReqBench) WithClientCert(cert tls.Certificate) *ReqBench {
+	if rb.tlsConfig == nil {
+		rb.tlsConfig = &tls.Config{}
+	}
+	rb.tlsConfig.Certificates = []tls.Certificate{cert}
+
+	return rb
+}
+
+// WithRootCAs to trust the server certificates issued by the pool,
+// e.g. the pool of a certs.CA
+func (rb *ReqBench) WithRootCAs(pool *x509.CertPool) *ReqBench {
+	if rb.tlsConfig == nil {
+		rb.tlsConfig = &tls.Config{}
+	}
+	rb.tlsConfig.RootCAs = pool
+
+	return rb
+}
+
 // GET request method
 func (rb *ReqBench) GET(p string) *ReqBench {
 	rb.method = "GET"
@@ -241,15 +276,22 @@ func (rb *ReqBench) Run() {
 		fmt.Printf("%d connections with %d pipelining factor\n\n", rb.concurrent, rb.pipeline)
 	}
 
-	callHTTP(rb)
+	bodies := &bodyStats{}
+	callHTTP(rb, bodies)
+
+	decompressed := countReadRequest(bodies.decompressed())
+	if bodies.estimated() {
+		decompressed = "~" + decompressed + " estimated"
+	}
 
 	fmt.Println("Req/Bytes counts sampled once per second.")
 	fmt.Printf("%s requests in %.2fs, %s read\n", countRequest(totalReq), math.Round(time.Since(start).Seconds()), countReadRequest(size))
+	fmt.Printf("%s of response bodies read (%s decompressed)\n", countReadRequest(bodies.read()), decompressed)
 	fmt.Printf("%s 2xx responses and %s non 2xx responses\n", countRequest(respOK), countRequest(reqError))
 	fmt.Printf("%s errors (%s timeouts)\n", countRequest(reqError), countRequest(timeout))
 }
 
-func callHTTP(rb *ReqBench) {
+func callHTTP(rb *ReqBench, bodies *bodyStats) {
 	latency := histogram.New()
 	reqBytes := histogram.New().SetTimeSleep(rb.duration)
 
@@ -282,6 +324,7 @@ func callHTTP(rb *ReqBench) {
 		c := fasthttp.Client{
 			Name:            "UjiHTTP/Benchmark",
 			MaxConnsPerHost: rb.concurrent,
+			TLSConfig:       rb.tlsConfig,
 		}
 
 		for j := 0; j < rb.pipeline; j++ {
@@ -308,6 +351,10 @@ func callHTTP(rb *ReqBench) {
 					req.Header.Set("Content-Type", rb.contentType)
 				}
 
+				if rb.accept != "" && len(req.Header.Peek("Accept-Encoding")) == 0 {
+					req.Header.Set("Accept-Encoding", rb.accept)
+				}
+
 				resp := fasthttp.AcquireResponse()
 				defer fasthttp.ReleaseResponse(resp)
 
@@ -322,6 +369,7 @@ func callHTTP(rb *ReqBench) {
 					} else {
 						latency.AddTime(time.Since(start))
 						size += int64(len(resp.Body()))
+						bodies.record(resp)
 						resp.Header.VisitAll(func(key, value []byte) {
 							size += int64(len(key) + len(value))
 						})
@@ -343,6 +391,71 @@ func callHTTP(rb *ReqBench) {
 	reqBytes.CalcReqBytes()
 }
 
+// bodyStats counts the response bodies of a run. A compressed body is not decoded
+// in the load loop, the first one is copied and decoded after the run.
+type bodyStats struct {
+	mu sync.Mutex
+	// wire is the size of the bodies as read, plain the size of the uncompressed ones
+	wire   int64
+	plain  int64
+	enc    string
+	sample []byte
+}
+
+func (s *bodyStats) record(resp *fasthttp.Response) {
+	n := int64(len(resp.Body()))
+	enc := resp.Header.Peek("Content-Encoding")
+	compressed := len(enc) > 0 && compression.Supported(string(enc))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.wire += n
+	if !compressed {
+		s.plain += n
+		return
+	}
+	if s.sample == nil {
+		s.enc = string(enc)
+		s.sample = append([]byte{}, resp.Body()...)
+	}
+}
+
+// read returns the size of the bodies as read, without the headers
+func (s *bodyStats) read() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.wire
+}
+
+// estimated reports whether the decompressed size holds compressed bodies
+func (s *bodyStats) estimated() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.wire > s.plain
+}
+
+// decompressed returns the size of the bodies after decompression, the compressed bodies
+// are estimated with the compression ratio of the sample
+func (s *bodyStats) decompressed() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoded := s.wire - s.plain
+	if encoded == 0 || len(s.sample) == 0 {
+		return s.wire
+	}
+
+	b, e := compression.Decode(s.enc, s.sample)
+	if e != nil {
+		return s.wire
+	}
+
+	return s.plain + encoded*int64(len(b))/int64(len(s.sample))
+}
+
 func countRequest(c int64) string {
 	var total string
 	if c < 1000 {
@@ -0,0 +1,307 @@
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/KodepandaID/ujihttp/pkg/mask"
+	"gopkg.in/yaml.v3"
+)
+
+// Mode decides whether a recorder sends the requests or replays a cassette
+type Mode int
+
+// Recorder modes
+const (
+	// Auto records when the cassette file does not exist and replays it otherwise
+	Auto Mode = iota
+	// Record sends every request and overwrites the cassette on Save
+	Record
+	// Replay answers from the cassette without sending any request
+	Replay
+)
+
+// Interaction is a recorded request with its response
+type Interaction struct {
+	Request  Request  `yaml:"request"`
+	Response Response `yaml:"response"`
+}
+
+// Request is a recorded request, the headers, URL and body are masked
+type Request struct {
+	Method string      `yaml:"method"`
+	URL    string      `yaml:"url"`
+	Header http.Header `yaml:"headers,omitempty"`
+	Body   string      `yaml:"body,omitempty"`
+}
+
+// Response is a recorded response, the headers and body are masked
+type Response struct {
+	Status int         `yaml:"status"`
+	Header http.Header `yaml:"headers,omitempty"`
+	Body   string      `yaml:"body,omitempty"`
+}
+
+// Cassette is the file holding the recorded interactions
+type Cassette struct {
+	Version      int            `yaml:"version"`
+	Interactions []*Interaction `yaml:"interactions"`
+}
+
+// MatchFunc reports whether an interaction answers a request, the request URL
+// and body are masked like the recorded ones
+type MatchFunc func(method, url string, body []byte, i *Interaction) bool
+
+// MatchMethodURLBody matches the method, the URL and the body, it is the default rule
+func MatchMethodURLBody(method, url string, body []byte, i *Interaction) bool {
+	return MatchMethodURL(method, url, body, i) && string(body) == i.Request.Body
+}
+
+// MatchMethodURL matches the method and the URL
+func MatchMethodURL(method, url string, body []byte, i *Interaction) bool {
+	return method == i.Request.Method && url == i.Request.URL
+}
+
+// Options of a recorder, every field is optional
+type Options struct {
+	Mode Mode
+	// Transport sends the requests when recording, http.DefaultTransport when not set
+	Transport http.RoundTripper
+	// Match is MatchMethodURLBody when not set
+	Match MatchFunc
+	// Mask hides the header, URL and body values before they are saved,
+	// mask.Default when not set. A replayed response holds the masked values.
+	Mask *mask.Policy
+}
+
+// Recorder is a http.RoundTripper recording interactions to a YAML cassette
+// or replaying them
+type Recorder struct {
+	mu       sync.Mutex
+	path     string
+	mode     Mode
+	opts     Options
+	cassette *Cassette
+	used     []bool
+	misses   []string
+}
+
+// New to open the cassette at path, the .yaml extension is added when missing
+func New(path string, opts ...Options) (*Recorder, error) {
+	o := Options{}
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Transport == nil {
+		o.Transport = http.DefaultTransport
+	}
+	if o.Match == nil {
+		o.Match = MatchMethodURLBody
+	}
+	if o.Mask == nil {
+		o.Mask = mask.Default
+	}
+
+	if filepath.Ext(path) != ".yaml" && filepath.Ext(path) != ".yml" {
+		path += ".yaml"
+	}
+
+	r := &Recorder{path: path, mode: o.Mode, opts: o, cassette: &Cassette{Version: 1}}
+
+	b, e := os.ReadFile(path)
+	switch {
+	case e == nil && r.mode != Record:
+		r.mode = Replay
+		if e := yaml.Unmarshal(b, r.cassette); e != nil {
+			return nil, fmt.Errorf("cassette %s: %v", path, e)
+		}
+	case errors.Is(e, os.ErrNotExist) && r.mode == Replay:
+		return nil, fmt.Errorf("cassette %s does not exist", path)
+	case errors.Is(e, os.ErrNotExist) || r.mode == Record:
+		r.mode = Record
+	default:
+		return nil, e
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+
+	return r, nil
+}
+
+// Mode returns Record or Replay
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Misses returns the requests no interaction answered when replaying
+func (r *Recorder) Misses() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string{}, r.misses...)
+}
+
+// Unused returns the recorded interactions no request used when replaying,
+// as "METHOD url"
+func (r *Recorder) Unused() []string {
+	if r.mode != Replay {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	unused := []string{}
+	for n, i := range r.cassette.Interactions {
+		if !r.used[n] {
+			unused = append(unused, i.Request.Method+" "+i.Request.URL)
+		}
+	}
+
+	return unused
+}
+
+// RoundTrip sends the request and records it, or answers it from the cassette.
+// When replaying, each interaction answers a single request.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := []byte{}
+	if req.Body != nil {
+		b, e := io.ReadAll(req.Body)
+		req.Body.Close()
+		if e != nil {
+			return nil, e
+		}
+		body = b
+	}
+	url := r.opts.Mask.URL(req.URL.String())
+	masked := r.opts.Mask.Body(req.Header.Get("Content-Type"), body)
+
+	if r.mode == Replay {
+		return r.replay(req, url, masked)
+	}
+
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	resp, e := r.opts.Transport.RoundTrip(out)
+	if e != nil {
+		return nil, e
+	}
+
+	respBody, e := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if e != nil {
+		return nil, e
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    url,
+			Header: r.opts.Mask.Header(req.Header),
+			Body:   string(masked),
+		},
+		Response: Response{
+			Status: resp.StatusCode,
+			Header: r.opts.Mask.Header(resp.Header),
+			Body:   string(r.opts.Mask.Body(resp.Header.Get("Content-Type"), respBody)),
+		},
+	})
+	r.used = append(r.used, true)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, url string, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for n, i := range r.cassette.Interactions {
+		if r.used[n] || !r.opts.Match(req.Method, url, body, i) {
+			continue
+		}
+		r.used[n] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.Response.Status, http.StatusText(i.Response.Status)),
+			StatusCode:    i.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        i.Response.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(i.Response.Body)),
+			ContentLength: int64(len(i.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	miss := req.Method + " " + url
+	r.misses = append(r.misses, miss)
+
+	return nil, fmt.Errorf("cassette %s: no recorded interaction for %s", r.path, miss)
+}
+
+// Save to write the recorded interactions, it does nothing when replaying
+func (r *Recorder) Save() error {
+	if r.mode != Record {
+		return nil
+	}
+
+	r.mu.Lock()
+	b, e := yaml.Marshal(r.cassette)
+	r.mu.Unlock()
+	if e != nil {
+		return e
+	}
+
+	if e := os.MkdirAll(filepath.Dir(r.path), 0755); e != nil {
+		return e
+	}
+
+	return os.WriteFile(r.path, b, 0644)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx holding the recorder
+func NewContext(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, contextKey{}, r)
+}
+
+// FromContext returns the recorder held by ctx, nil when there is none
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(contextKey{}).(*Recorder)
+
+	return r
+}
+
+// Transport returns a http.RoundTripper sending a request with the recorder held by
+// its context, or with next when there is none. next is http.DefaultTransport when nil.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return contextTransport{next: next}
+}
+
+type contextTransport struct {
+	next http.RoundTripper
+}
+
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r := FromContext(req.Context()); r != nil {
+		return r.RoundTrip(req)
+	}
+
+	return t.next.RoundTrip(req)
+}
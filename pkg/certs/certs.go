@@ -0,0 +1,218 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"time"
+)
+
+// KeyType is the algorithm of a generated key
+type KeyType string
+
+// Key types of the generated certificates
+const (
+	ECDSA   KeyType = "ecdsa"
+	RSA     KeyType = "rsa"
+	Ed25519 KeyType = "ed25519"
+)
+
+// defaultValidity is how long a certificate is valid when NotAfter is not set
+const defaultValidity = 24 * time.Hour
+
+// Options of a generated certificate, every field is optional
+type Options struct {
+	CommonName   string
+	Organization string
+	// DNSNames and IPAddresses are the SANs, a server certificate
+	// is valid for localhost, 127.0.0.1 and ::1 when both are empty
+	DNSNames    []string
+	IPAddresses []net.IP
+	// NotBefore is an hour ago and NotAfter is a day later when not set,
+	// set NotAfter in the past for an expired certificate
+	NotBefore time.Time
+	NotAfter  time.Time
+	// KeyType is ECDSA P-256 when not set
+	KeyType KeyType
+	// RSABits is the RSA key size, 2048 when not set
+	RSABits int
+}
+
+// CA is an in-memory certificate authority issuing throwaway certificates
+type CA struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+	// TLS is the CA certificate with its key
+	TLS tls.Certificate
+}
+
+// NewCA to generate a self-signed certificate authority
+func NewCA(opts ...Options) *CA {
+	o := options(opts)
+	if o.CommonName == "" {
+		o.CommonName = "UjiHTTP Test CA"
+	}
+
+	key := newKey(o)
+	tpl := template(o)
+	tpl.IsCA = true
+	tpl.BasicConstraintsValid = true
+	tpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	der, e := x509.CreateCertificate(rand.Reader, tpl, tpl, key.Public(), key)
+	if e != nil {
+		panic(e)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	return &CA{
+		Cert: cert,
+		Key:  key,
+		TLS:  tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert},
+	}
+}
+
+// Server to issue a server certificate, valid for localhost, 127.0.0.1 and ::1 by default
+func (ca *CA) Server(opts ...Options) tls.Certificate {
+	o := options(opts)
+	if o.CommonName == "" {
+		o.CommonName = "localhost"
+	}
+	if len(o.DNSNames) == 0 && len(o.IPAddresses) == 0 {
+		o.DNSNames = []string{"localhost"}
+		o.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	}
+
+	return ca.issue(o, x509.ExtKeyUsageServerAuth)
+}
+
+// Client to issue a client certificate
+func (ca *CA) Client(opts ...Options) tls.Certificate {
+	o := options(opts)
+	if o.CommonName == "" {
+		o.CommonName = "UjiHTTP Client"
+	}
+
+	return ca.issue(o, x509.ExtKeyUsageClientAuth)
+}
+
+// Pool returns a pool holding the CA certificate, to trust the certificates it issues
+func (ca *CA) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Cert)
+
+	return pool
+}
+
+// PEM returns the CA certificate PEM encoded
+func (ca *CA) PEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Cert.Raw})
+}
+
+// PEM returns the certificate chain and the private key of a certificate PEM encoded
+func PEM(cert tls.Certificate) (certPEM, keyPEM []byte) {
+	for _, der := range cert.Certificate {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	key, e := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if e != nil {
+		panic(e)
+	}
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})
+
+	return certPEM, keyPEM
+}
+
+func (ca *CA) issue(o Options, usage x509.ExtKeyUsage) tls.Certificate {
+	key := newKey(o)
+	tpl := template(o)
+	tpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	tpl.KeyUsage = x509.KeyUsageDigitalSignature
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		tpl.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	der, e := x509.CreateCertificate(rand.Reader, tpl, ca.Cert, key.Public(), ca.Key)
+	if e != nil {
+		panic(e)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	return tls.Certificate{
+		Certificate: [][]byte{der, ca.Cert.Raw},
+		PrivateKey:  key,
+		Leaf:        cert,
+	}
+}
+
+func options(opts []Options) Options {
+	o := Options{}
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	if o.NotBefore.IsZero() {
+		o.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if o.NotAfter.IsZero() {
+		o.NotAfter = time.Now().Add(defaultValidity)
+	}
+
+	return o
+}
+
+func template(o Options) *x509.Certificate {
+	serial, e := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if e != nil {
+		panic(e)
+	}
+
+	subject := pkix.Name{CommonName: o.CommonName}
+	if o.Organization != "" {
+		subject.Organization = []string{o.Organization}
+	}
+
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      subject,
+		DNSNames:     o.DNSNames,
+		IPAddresses:  o.IPAddresses,
+		NotBefore:    o.NotBefore,
+		NotAfter:     o.NotAfter,
+	}
+}
+
+func newKey(o Options) crypto.Signer {
+	var key crypto.Signer
+	var e error
+
+	switch o.KeyType {
+	case RSA:
+		bits := o.RSABits
+		if bits == 0 {
+			bits = 2048
+		}
+		key, e = rsa.GenerateKey(rand.Reader, bits)
+	case Ed25519:
+		_, key, e = ed25519.GenerateKey(rand.Reader)
+	case ECDSA, "":
+		key, e = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		panic("certs: unsupported key type " + string(o.KeyType))
+	}
+	if e != nil {
+		panic(e)
+	}
+
+	return key
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/i582/cfmt"
+)
+
+// AttemptData is an attempt of a polled request
+type AttemptData struct {
+	Attempt  int
+	Method   string
+	Path     string
+	Code     int
+	Duration time.Duration
+	// Problem is empty for the attempt meeting the condition
+	Problem string
+}
+
+// WriteAttempts to write the attempts of a polled request on w
+func WriteAttempts(w io.Writer, attempts []AttemptData) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow(
+		cfmt.Sprintf("{{%s}}::bold", "ATTEMPT"),
+		cfmt.Sprintf("{{%s}}::bold", "METHOD"),
+		cfmt.Sprintf("{{%s}}::bold", "PATH"),
+		cfmt.Sprintf("{{%s}}::bold", "StatusCode"),
+		cfmt.Sprintf("{{%s}}::bold", "AFTER"),
+		cfmt.Sprintf("{{%s}}::bold", "RESULT"))
+
+	for _, a := range attempts {
+		color := "green"
+		result := "ok"
+		if a.Problem != "" {
+			color, result = "yellow", a.Problem
+		}
+
+		table.AddRow(
+			a.Attempt,
+			cfmt.Sprintf("{{%s}}::"+color+"|bold", a.Method),
+			a.Path,
+			cfmt.Sprintf("{{%d %s}}::"+color+"|bold", a.Code, http.StatusText(a.Code)),
+			a.Duration.Round(time.Millisecond),
+			cfmt.Sprintf("{{%s}}::"+color+"|bold", result))
+	}
+
+	cfmt.Fprintln(w, table)
+}
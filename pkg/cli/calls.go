@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"io"
+
+	"github.com/gosuri/uitable"
+	"github.com/i582/cfmt"
+)
+
+// CallData is an expected or received call of a mock server
+type CallData struct {
+	Method string
+	Path   string
+	Calls  int
+	// Expected is 0 for a call no expectation matched
+	Expected int
+	// Problem is empty when the expectation is met
+	Problem string
+}
+
+// WriteCalls to write the calls of a mock server on w
+func WriteCalls(w io.Writer, calls []CallData) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow(
+		cfmt.Sprintf("{{%s}}::bold", "METHOD"),
+		cfmt.Sprintf("{{%s}}::bold", "PATH"),
+		cfmt.Sprintf("{{%s}}::bold", "CALLS"),
+		cfmt.Sprintf("{{%s}}::bold", "RESULT"))
+
+	for _, c := range calls {
+		calls := cfmt.Sprintf("{{%d}}::bold", c.Calls)
+		if c.Expected > 0 {
+			calls = cfmt.Sprintf("{{%d/%d}}::bold", c.Calls, c.Expected)
+		}
+
+		if c.Problem == "" {
+			table.AddRow(
+				cfmt.Sprintf("{{%s}}::green|bold", c.Method),
+				c.Path,
+				calls,
+				cfmt.Sprintf("{{%s}}::green|bold", "ok"))
+			continue
+		}
+
+		table.AddRow(
+			cfmt.Sprintf("{{%s}}::red|bold", c.Method),
+			c.Path,
+			calls,
+			cfmt.Sprintf("{{%s}}::red|bold", c.Problem))
+	}
+
+	cfmt.Fprintln(w, table)
+}
@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"io"
+	"os"
 	"time"
 
 	"github.com/gosuri/uitable"
@@ -19,6 +21,11 @@ type DebugData struct {
 
 // WriteDebug to showing on terminal
 func WriteDebug(d *DebugData) {
+	WriteDebugTo(os.Stdout, d)
+}
+
+// WriteDebugTo to write the debug row on w
+func WriteDebugTo(w io.Writer, d *DebugData) {
 	table := uitable.New()
 	table.MaxColWidth = 50
 
@@ -46,5 +53,5 @@ func WriteDebug(d *DebugData) {
 		code = cfmt.Sprintf("{{%d %s}}::red|bold", d.Code, d.CodeStatus)
 	}
 	table.AddRow(method, d.Path, size, duration, code)
-	cfmt.Println(table)
+	cfmt.Fprintln(w, table)
 }
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"io"
+
+	"github.com/gosuri/uitable"
+	"github.com/i582/cfmt"
+)
+
+// DivergenceData is a difference between the responses of two handlers to a request
+type DivergenceData struct {
+	Method string
+	Path   string
+	Field  string
+	Old    string
+	New    string
+}
+
+// WriteDivergences to write the differences between two handlers on w
+func WriteDivergences(w io.Writer, rows []DivergenceData) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow(
+		cfmt.Sprintf("{{%s}}::bold", "METHOD"),
+		cfmt.Sprintf("{{%s}}::bold", "PATH"),
+		cfmt.Sprintf("{{%s}}::bold", "FIELD"),
+		cfmt.Sprintf("{{%s}}::bold", "OLD"),
+		cfmt.Sprintf("{{%s}}::bold", "NEW"))
+
+	for _, d := range rows {
+		table.AddRow(
+			cfmt.Sprintf("{{%s}}::red|bold", d.Method),
+			d.Path,
+			cfmt.Sprintf("{{%s}}::bold", d.Field),
+			d.Old,
+			d.New)
+	}
+
+	cfmt.Fprintln(w, table)
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"io"
+
+	"github.com/gosuri/uitable"
+	"github.com/i582/cfmt"
+)
+
+// MatrixData is the result of an authorization matrix, a row by endpoint and a column by role
+type MatrixData struct {
+	Roles []string
+	Rows  []MatrixRow
+}
+
+// MatrixRow is the result of an endpoint for every role
+type MatrixRow struct {
+	Method string
+	Path   string
+	Cells  []MatrixCell
+}
+
+// MatrixCell is the response of an endpoint to a role
+type MatrixCell struct {
+	Code     int
+	Expected string
+	OK       bool
+}
+
+// WriteMatrix to write an authorization matrix on w, the mismatches are highlighted
+func WriteMatrix(w io.Writer, d *MatrixData) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	header := []interface{}{
+		cfmt.Sprintf("{{%s}}::bold", "METHOD"),
+		cfmt.Sprintf("{{%s}}::bold", "PATH"),
+	}
+	for _, r := range d.Roles {
+		header = append(header, cfmt.Sprintf("{{%s}}::bold", r))
+	}
+	table.AddRow(header...)
+
+	for _, row := range d.Rows {
+		cells := []interface{}{cfmt.Sprintf("{{%s}}::bold", row.Method), row.Path}
+		for _, c := range row.Cells {
+			if c.OK {
+				cells = append(cells, cfmt.Sprintf("{{%d}}::green|bold", c.Code))
+				continue
+			}
+			cells = append(cells, cfmt.Sprintf("{{%d want %s}}::red|bold", c.Code, c.Expected))
+		}
+		table.AddRow(cells...)
+	}
+
+	cfmt.Fprintln(w, table)
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"io"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/i582/cfmt"
+)
+
+// TimingData is the duration summary of a repeated request
+type TimingData struct {
+	Method string
+	Path   string
+	Runs   int
+	Min    time.Duration
+	Median time.Duration
+	P95    time.Duration
+	Max    time.Duration
+}
+
+// WriteTiming to write the duration summary of a repeated request on w
+func WriteTiming(w io.Writer, d *TimingData) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow(
+		cfmt.Sprintf("{{%s}}::bold", "METHOD"),
+		cfmt.Sprintf("{{%s}}::bold", "PATH"),
+		cfmt.Sprintf("{{%s}}::bold", "RUNS"),
+		cfmt.Sprintf("{{%s}}::bold", "MIN"),
+		cfmt.Sprintf("{{%s}}::bold", "MEDIAN"),
+		cfmt.Sprintf("{{%s}}::bold", "P95"),
+		cfmt.Sprintf("{{%s}}::bold", "MAX"))
+
+	table.AddRow(
+		cfmt.Sprintf("{{%s}}::green|bold", d.Method),
+		d.Path,
+		d.Runs,
+		cfmt.Sprintf("{{%s}}::green|bold", d.Min),
+		cfmt.Sprintf("{{%s}}::green|bold", d.Median),
+		cfmt.Sprintf("{{%s}}::green|bold", d.P95),
+		cfmt.Sprintf("{{%s}}::green|bold", d.Max))
+	cfmt.Fprintln(w, table)
+}
@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/i582/cfmt"
+)
+
+// Message is a request or response shown on verbose debug
+type Message struct {
+	Header  http.Header
+	Cookies []*http.Cookie
+	Body    []byte
+}
+
+// VerboseData for showing the full request and response on terminal
+type VerboseData struct {
+	DebugData
+	Proto     string
+	Request   Message
+	Response  Message
+	BodyLimit int
+}
+
+// WriteVerbose to write the summary row followed by the request and response dump
+func WriteVerbose(w io.Writer, d *VerboseData) {
+	WriteDebugTo(w, &d.DebugData)
+
+	proto := d.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+
+	cfmt.Fprintf(w, "{{%s}}::cyan|bold\n", "Request")
+	fmt.Fprintf(w, "> %s %s %s\n", d.Method, d.Path, proto)
+	writeHeader(w, ">", d.Request.Header)
+	writeCookies(w, ">", d.Request.Cookies)
+	fmt.Fprintln(w, ">")
+	writeBody(w, d.Request.Header.Get("Content-Type"), d.Request.Body, d.BodyLimit)
+
+	cfmt.Fprintf(w, "{{%s}}::cyan|bold\n", "Response")
+	fmt.Fprintf(w, "< %s %d %s\n", proto, d.Code, d.CodeStatus)
+	writeHeader(w, "<", d.Response.Header)
+	writeCookies(w, "<", d.Response.Cookies)
+	fmt.Fprintln(w, "<")
+	writeBody(w, d.Response.Header.Get("Content-Type"), d.Response.Body, d.BodyLimit)
+	fmt.Fprintln(w)
+}
+
+func writeHeader(w io.Writer, prefix string, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(w, "%s %s: %s\n", prefix, k, v)
+		}
+	}
+}
+
+func writeCookies(w io.Writer, prefix string, cookies []*http.Cookie) {
+	for _, c := range cookies {
+		fmt.Fprintf(w, "%s cookie %s\n", prefix, c.String())
+	}
+}
+
+func writeBody(w io.Writer, ct string, b []byte, limit int) {
+	if len(b) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, FormatBody(ct, b, limit))
+}
+
+// FormatBody to render a body for terminal output,
+// JSON and XML are indented, multipart parts are listed and binary is shown as a hex summary
+func FormatBody(ct string, b []byte, limit int) string {
+	mt, params, _ := mime.ParseMediaType(ct)
+
+	switch {
+	case strings.HasPrefix(mt, "multipart/"):
+		if s, e := formatMultipart(b, params["boundary"], limit); e == nil {
+			return s
+		}
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		out := &bytes.Buffer{}
+		if e := json.Indent(out, b, "", "  "); e == nil {
+			return truncate(out.Bytes(), limit)
+		}
+	case mt == "application/xml" || mt == "text/xml" || strings.HasSuffix(mt, "+xml"):
+		if out, e := indentXML(b); e == nil {
+			return truncate(out, limit)
+		}
+	}
+
+	if isBinary(b) {
+		return formatBinary(b)
+	}
+
+	return truncate(b, limit)
+}
+
+func formatMultipart(b []byte, boundary string, limit int) (string, error) {
+	if boundary == "" {
+		return "", fmt.Errorf("missing boundary")
+	}
+
+	out := &bytes.Buffer{}
+	r := multipart.NewReader(bytes.NewReader(b), boundary)
+	for {
+		p, e := r.NextPart()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return "", e
+		}
+
+		data, e := ioutil.ReadAll(p)
+		if e != nil {
+			return "", e
+		}
+
+		if p.FileName() != "" {
+			fmt.Fprintf(out, "part %q file %q (%s, %d B)\n", p.FormName(), p.FileName(), p.Header.Get("Content-Type"), len(data))
+			continue
+		}
+		fmt.Fprintf(out, "part %q = %s\n", p.FormName(), truncate(data, limit))
+	}
+
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
+
+func indentXML(b []byte) ([]byte, error) {
+	out := &bytes.Buffer{}
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+
+	for {
+		t, e := dec.Token()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, e
+		}
+		if cd, ok := t.(xml.CharData); ok && len(bytes.TrimSpace(cd)) == 0 {
+			continue
+		}
+		if e := enc.EncodeToken(t); e != nil {
+			return nil, e
+		}
+	}
+	if e := enc.Flush(); e != nil {
+		return nil, e
+	}
+
+	return out.Bytes(), nil
+}
+
+func isBinary(b []byte) bool {
+	return !utf8.Valid(b) || bytes.IndexByte(b, 0) >= 0
+}
+
+func formatBinary(b []byte) string {
+	n := len(b)
+	if n > 64 {
+		n = 64
+	}
+
+	return fmt.Sprintf("binary body, %d B\n%s", len(b), strings.TrimSuffix(hex.Dump(b[:n]), "\n"))
+}
+
+func truncate(b []byte, limit int) string {
+	if limit <= 0 || len(b) <= limit {
+		return string(b)
+	}
+
+	return fmt.Sprintf("%s... (%d B truncated)", b[:limit], len(b)-limit)
+}
@@ -0,0 +1,100 @@
+package compression
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Supported content codings
+const (
+	Gzip    = "gzip"
+	Deflate = "deflate"
+	Brotli  = "br"
+)
+
+// AcceptAll is an Accept-Encoding value listing every supported coding
+const AcceptAll = "gzip, deflate, br"
+
+// Supported reports whether the content coding can be encoded and decoded
+func Supported(enc string) bool {
+	switch normalize(enc) {
+	case Gzip, Deflate, Brotli:
+		return true
+	}
+
+	return false
+}
+
+// Encode to compress b with the content coding
+func Encode(enc string, b []byte) ([]byte, error) {
+	out := &bytes.Buffer{}
+
+	var w io.WriteCloser
+	switch normalize(enc) {
+	case Gzip:
+		w = gzip.NewWriter(out)
+	case Deflate:
+		w = zlib.NewWriter(out)
+	case Brotli:
+		w = brotli.NewWriter(out)
+	default:
+		return nil, fmt.Errorf("compression: unsupported content coding %q", enc)
+	}
+
+	if _, e := w.Write(b); e != nil {
+		return nil, e
+	}
+	if e := w.Close(); e != nil {
+		return nil, e
+	}
+
+	return out.Bytes(), nil
+}
+
+// Decode to decompress b encoded with the content coding,
+// deflate accepts both zlib wrapped and raw streams
+func Decode(enc string, b []byte) ([]byte, error) {
+	var r io.Reader
+	switch normalize(enc) {
+	case Gzip:
+		gr, e := gzip.NewReader(bytes.NewReader(b))
+		if e != nil {
+			return nil, e
+		}
+		defer gr.Close()
+		r = gr
+	case Deflate:
+		zr, e := zlib.NewReader(bytes.NewReader(b))
+		if e != nil {
+			fr := flate.NewReader(bytes.NewReader(b))
+			defer fr.Close()
+			r = fr
+			break
+		}
+		defer zr.Close()
+		r = zr
+	case Brotli:
+		r = brotli.NewReader(bytes.NewReader(b))
+	default:
+		return nil, fmt.Errorf("compression: unsupported content coding %q", enc)
+	}
+
+	return ioutil.ReadAll(r)
+}
+
+func normalize(enc string) string {
+	enc = strings.ToLower(strings.TrimSpace(enc))
+	if enc == "x-gzip" {
+		return Gzip
+	}
+
+	return enc
+}
@@ -0,0 +1,270 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gosuri/uitable"
+	"github.com/i582/cfmt"
+)
+
+// Route is a registered route of a router
+//
+// Path params can be written as :name, {name} or {name:regexp},
+// wildcards as *name or {name...}. A Prefix route matches every path under it.
+type Route struct {
+	Method string
+	Path   string
+	Prefix bool
+}
+
+// RouteReport is the observed traffic of a single route
+type RouteReport struct {
+	Route
+	Hits  int
+	Codes map[int]int
+}
+
+// Coverage collects which registered routes were hit by the tests
+type Coverage struct {
+	mu        sync.Mutex
+	routes    []*RouteReport
+	unmatched map[string]map[int]int
+}
+
+// New to start a route coverage from registered routes
+func New(routes []Route) *Coverage {
+	c := &Coverage{
+		unmatched: map[string]map[int]int{},
+	}
+
+	return c.Add(routes...)
+}
+
+// Add to register more routes
+func (c *Coverage) Add(routes ...Route) *Coverage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, r := range routes {
+		r.Method = strings.ToUpper(r.Method)
+		c.routes = append(c.routes, &RouteReport{
+			Route: r,
+			Codes: map[int]int{},
+		})
+	}
+
+	return c
+}
+
+// Record to count a request against the route matching it
+func (c *Coverage) Record(method, path string, code int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	method = strings.ToUpper(method)
+
+	var best *RouteReport
+	bestScore := -1
+	for _, r := range c.routes {
+		if r.Method != "" && r.Method != "ANY" && r.Method != "*" && r.Method != method {
+			continue
+		}
+
+		if score, ok := match(r.Route, path); ok && score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+
+	if best == nil {
+		key := method + " " + path
+		if c.unmatched[key] == nil {
+			c.unmatched[key] = map[int]int{}
+		}
+		c.unmatched[key][code]++
+		return
+	}
+
+	best.Hits++
+	best.Codes[code]++
+}
+
+// Report returns the traffic of every registered route
+func (c *Coverage) Report() []RouteReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reports := make([]RouteReport, 0, len(c.routes))
+	for _, r := range c.routes {
+		codes := map[int]int{}
+		for k, v := range r.Codes {
+			codes[k] = v
+		}
+		reports = append(reports, RouteReport{Route: r.Route, Hits: r.Hits, Codes: codes})
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		if reports[i].Path == reports[j].Path {
+			return reports[i].Method < reports[j].Method
+		}
+		return reports[i].Path < reports[j].Path
+	})
+
+	return reports
+}
+
+// Uncovered returns the routes no test has hit
+func (c *Coverage) Uncovered() []Route {
+	routes := []Route{}
+	for _, r := range c.Report() {
+		if r.Hits == 0 {
+			routes = append(routes, r.Route)
+		}
+	}
+
+	return routes
+}
+
+// Percent returns the percentage of routes hit by the tests
+func (c *Coverage) Percent() float64 {
+	reports := c.Report()
+	if len(reports) == 0 {
+		return 0
+	}
+
+	return float64(len(reports)-len(c.Uncovered())) / float64(len(reports)) * 100
+}
+
+// Write to print the coverage table
+func (c *Coverage) Write(w io.Writer) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+
+	table.AddRow(
+		cfmt.Sprintf("{{%s}}::bold", "METHOD"),
+		cfmt.Sprintf("{{%s}}::bold", "PATH"),
+		cfmt.Sprintf("{{%s}}::bold", "HITS"),
+		cfmt.Sprintf("{{%s}}::bold", "StatusCode"))
+
+	for _, r := range c.Report() {
+		method := r.Method
+		if method == "" {
+			method = "ANY"
+		}
+
+		if r.Hits == 0 {
+			table.AddRow(
+				cfmt.Sprintf("{{%s}}::red|bold", method),
+				r.Path,
+				cfmt.Sprintf("{{%d}}::red|bold", r.Hits),
+				cfmt.Sprintf("{{%s}}::red|bold", "not covered"))
+			continue
+		}
+
+		table.AddRow(
+			cfmt.Sprintf("{{%s}}::green|bold", method),
+			r.Path,
+			cfmt.Sprintf("{{%d}}::bold", r.Hits),
+			formatCodes(r.Codes))
+	}
+
+	c.mu.Lock()
+	unmatched := make([]string, 0, len(c.unmatched))
+	for k := range c.unmatched {
+		unmatched = append(unmatched, k)
+	}
+	sort.Strings(unmatched)
+	for _, k := range unmatched {
+		parts := strings.SplitN(k, " ", 2)
+		hits := 0
+		for _, n := range c.unmatched[k] {
+			hits += n
+		}
+		table.AddRow(
+			cfmt.Sprintf("{{%s}}::yellow|bold", parts[0]),
+			parts[1],
+			cfmt.Sprintf("{{%d}}::bold", hits),
+			cfmt.Sprintf("{{%s}}::yellow|bold", "no route: ")+formatCodes(c.unmatched[k]))
+	}
+	c.mu.Unlock()
+
+	cfmt.Fprintln(w, table)
+	cfmt.Fprintf(w, "{{%.1f%%}}::bold of routes covered\n", c.Percent())
+}
+
+func formatCodes(codes map[int]int) string {
+	keys := make([]int, 0, len(codes))
+	for k := range codes {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		color := "green"
+		if k >= 300 {
+			color = "red"
+		}
+		parts = append(parts, cfmt.Sprintf("{{%d %s}}::"+color+"|bold", k, http.StatusText(k))+fmt.Sprintf(" x%d", codes[k]))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// match reports whether the path matches the route template,
+// the score favours static segments over params and wildcards
+func match(r Route, path string) (int, bool) {
+	tpl := splitPath(r.Path)
+	segs := splitPath(path)
+
+	score := 0
+	for i, t := range tpl {
+		if isWildcard(t) {
+			return score, true
+		}
+		if i >= len(segs) {
+			return 0, false
+		}
+
+		if isParam(t) {
+			if segs[i] == "" {
+				return 0, false
+			}
+			score++
+			continue
+		}
+		if t != segs[i] {
+			return 0, false
+		}
+		score += 2
+	}
+
+	if len(segs) > len(tpl) {
+		return score, r.Prefix
+	}
+
+	return score + 1, true
+}
+
+func splitPath(p string) []string {
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return []string{}
+	}
+
+	return strings.Split(p, "/")
+}
+
+func isParam(s string) bool {
+	return strings.HasPrefix(s, ":") || (strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}"))
+}
+
+func isWildcard(s string) bool {
+	return strings.HasPrefix(s, "*") || strings.HasSuffix(s, "...}")
+}
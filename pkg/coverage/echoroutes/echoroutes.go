@@ -0,0 +1,19 @@
+package echoroutes
+
+import (
+	"github.com/KodepandaID/ujihttp/pkg/coverage"
+	"github.com/labstack/echo/v4"
+)
+
+// Routes returns the routes registered on an echo instance
+func Routes(e *echo.Echo) []coverage.Route {
+	routes := []coverage.Route{}
+	for _, r := range e.Routes() {
+		routes = append(routes, coverage.Route{
+			Method: r.Method,
+			Path:   r.Path,
+		})
+	}
+
+	return routes
+}
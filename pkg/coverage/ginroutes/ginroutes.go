@@ -0,0 +1,19 @@
+package ginroutes
+
+import (
+	"github.com/KodepandaID/ujihttp/pkg/coverage"
+	"github.com/gin-gonic/gin"
+)
+
+// Routes returns the routes registered on a gin engine
+func Routes(e *gin.Engine) []coverage.Route {
+	routes := []coverage.Route{}
+	for _, r := range e.Routes() {
+		routes = append(routes, coverage.Route{
+			Method: r.Method,
+			Path:   r.Path,
+		})
+	}
+
+	return routes
+}
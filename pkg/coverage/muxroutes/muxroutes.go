@@ -0,0 +1,43 @@
+package muxroutes
+
+import (
+	"strings"
+
+	"github.com/KodepandaID/ujihttp/pkg/coverage"
+	"github.com/gorilla/mux"
+)
+
+// Routes returns the routes registered on a gorilla mux router,
+// routes without a path template such as host-only matchers are skipped
+func Routes(r *mux.Router) []coverage.Route {
+	routes := []coverage.Route{}
+
+	r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, e := route.GetPathTemplate()
+		if e != nil || route.GetHandler() == nil {
+			return nil
+		}
+
+		// PathPrefix routes compile to a regexp without the end anchor
+		prefix := false
+		if re, e := route.GetPathRegexp(); e == nil && !strings.HasSuffix(re, "$") {
+			prefix = true
+		}
+
+		methods, e := route.GetMethods()
+		if e != nil {
+			methods = []string{""}
+		}
+		for _, m := range methods {
+			routes = append(routes, coverage.Route{
+				Method: m,
+				Path:   path,
+				Prefix: prefix,
+			})
+		}
+
+		return nil
+	})
+
+	return routes
+}
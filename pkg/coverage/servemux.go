@@ -0,0 +1,76 @@
+package coverage
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ServeMux is a http.ServeMux remembering the patterns registered on it,
+// since http.ServeMux cannot enumerate its routes
+type ServeMux struct {
+	*http.ServeMux
+	mu       sync.Mutex
+	patterns []string
+}
+
+// NewServeMux to start a ServeMux recording its routes
+func NewServeMux() *ServeMux {
+	return &ServeMux{
+		ServeMux: http.NewServeMux(),
+	}
+}
+
+// Handle registers the handler for the given pattern
+func (m *ServeMux) Handle(pattern string, h http.Handler) {
+	m.ServeMux.Handle(pattern, h)
+	m.record(pattern)
+}
+
+// HandleFunc registers the handler function for the given pattern
+func (m *ServeMux) HandleFunc(pattern string, h func(http.ResponseWriter, *http.Request)) {
+	m.ServeMux.HandleFunc(pattern, h)
+	m.record(pattern)
+}
+
+// Routes returns the registered routes
+func (m *ServeMux) Routes() []Route {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	routes := make([]Route, 0, len(m.patterns))
+	for _, p := range m.patterns {
+		routes = append(routes, ParsePattern(p))
+	}
+
+	return routes
+}
+
+func (m *ServeMux) record(pattern string) {
+	m.mu.Lock()
+	m.patterns = append(m.patterns, pattern)
+	m.mu.Unlock()
+}
+
+// ParsePattern to convert a http.ServeMux pattern such as "GET /users/{id}" into a Route
+func ParsePattern(pattern string) Route {
+	r := Route{}
+
+	pattern = strings.TrimSpace(pattern)
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		r.Method = strings.ToUpper(pattern[:i])
+		pattern = strings.TrimSpace(pattern[i:])
+	}
+	if i := strings.Index(pattern, "/"); i > 0 {
+		pattern = pattern[i:]
+	}
+
+	if strings.HasSuffix(pattern, "/{$}") {
+		pattern = strings.TrimSuffix(pattern, "{$}")
+	} else if strings.HasSuffix(pattern, "/") {
+		r.Prefix = true
+	}
+	r.Path = pattern
+
+	return r
+}
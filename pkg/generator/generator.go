@@ -0,0 +1,277 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/KodepandaID/ujihttp/pkg/openapi"
+)
+
+// Options for generating test skeletons
+type Options struct {
+	// Package is the package clause of the generated file
+	Package string
+	// Handler is the Go expression passed to Run, e.g. "Handler()"
+	Handler string
+	// Source is the spec file name written on the file header
+	Source string
+}
+
+type testCase struct {
+	name    string
+	summary string
+	method  string
+	path    string
+	headers map[string]interface{}
+	cookies map[string]interface{}
+	json    interface{}
+	form    map[string]interface{}
+	// urlEncoded sends the form as application/x-www-form-urlencoded
+	urlEncoded bool
+	status     int
+}
+
+// Generate to write Go test functions for every operation in the spec
+func Generate(s *openapi.Spec, o Options) ([]byte, error) {
+	if o.Package == "" {
+		o.Package = "main"
+	}
+	if o.Handler == "" {
+		o.Handler = "Handler()"
+	}
+
+	tests := &bytes.Buffer{}
+	names := map[string]int{}
+	for _, p := range s.SortedPaths() {
+		item := s.Paths[p]
+		if item == nil {
+			continue
+		}
+
+		ops := item.Operations()
+		for _, m := range openapi.Methods {
+			op, ok := ops[m]
+			if !ok {
+				continue
+			}
+
+			tc := newTestCase(s, p, m, item, op)
+			if n := names[tc.name]; n > 0 {
+				names[tc.name]++
+				tc.name = fmt.Sprintf("%s%d", tc.name, n+1)
+			} else {
+				names[tc.name] = 1
+			}
+			writeTest(tests, tc, o.Handler)
+		}
+	}
+
+	b := &bytes.Buffer{}
+	if o.Source != "" {
+		fmt.Fprintf(b, "// Generated by ujihttp gen tests from %s.\n\n", o.Source)
+	}
+	fmt.Fprintf(b, "package %s\n", o.Package)
+	// a spec without operations has no test using the imports
+	if tests.Len() > 0 {
+		b.WriteString("\nimport (\n\"net/http\"\n\"net/http/httptest\"\n\"testing\"\n\n\"github.com/KodepandaID/ujihttp\"\n)\n")
+	}
+	b.Write(tests.Bytes())
+
+	src, e := format.Source(b.Bytes())
+	if e != nil {
+		return b.Bytes(), fmt.Errorf("generator: formatting output: %v", e)
+	}
+
+	return src, nil
+}
+
+func newTestCase(s *openapi.Spec, p, m string, item *openapi.PathItem, op *openapi.Operation) *testCase {
+	tc := &testCase{
+		name:    testName(op.OperationID, m, p),
+		summary: op.Summary,
+		method:  m,
+		status:  op.ExpectedStatus(),
+	}
+
+	path := p
+	query := url.Values{}
+	for _, param := range s.Parameters(item, op) {
+		v := s.ParameterExample(param)
+		switch param.In {
+		case "path":
+			path = strings.Replace(path, "{"+param.Name+"}", url.PathEscape(fmt.Sprint(v)), -1)
+		case "query":
+			if param.Required || param.Example != nil {
+				query.Set(param.Name, fmt.Sprint(v))
+			}
+		case "header":
+			if param.Required {
+				if tc.headers == nil {
+					tc.headers = map[string]interface{}{}
+				}
+				tc.headers[param.Name] = v
+			}
+		case "cookie":
+			if param.Required {
+				if tc.cookies == nil {
+					tc.cookies = map[string]interface{}{}
+				}
+				tc.cookies[param.Name] = v
+			}
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	tc.path = path
+
+	if rb := s.RequestBody(op); rb != nil {
+		for _, ct := range sortedKeys(rb.Content) {
+			mt := rb.Content[ct]
+			if mt == nil {
+				continue
+			}
+
+			mediaType := strings.TrimSpace(strings.Split(ct, ";")[0])
+			if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+				tc.json = s.MediaExample(mt)
+				break
+			}
+			if mediaType == "multipart/form-data" || mediaType == "application/x-www-form-urlencoded" {
+				if form, ok := s.MediaExample(mt).(map[string]interface{}); ok {
+					tc.form = form
+					tc.urlEncoded = mediaType == "application/x-www-form-urlencoded"
+				}
+				break
+			}
+		}
+	}
+
+	return tc
+}
+
+func writeTest(b *bytes.Buffer, tc *testCase, handler string) {
+	b.WriteString("\n")
+	if tc.summary != "" {
+		fmt.Fprintf(b, "// %s %s\n", tc.name, tc.summary)
+	} else {
+		fmt.Fprintf(b, "// %s %s %s\n", tc.name, tc.method, tc.path)
+	}
+	fmt.Fprintf(b, "func %s(t *testing.T) {\n", tc.name)
+	b.WriteString("r := ujihttp.New()\n\nr.\n")
+	fmt.Fprintf(b, "%s(%s).\n", tc.method, strconv.Quote(tc.path))
+
+	if len(tc.headers) > 0 {
+		fmt.Fprintf(b, "WithHeader(%s).\n", stringMap(tc.headers))
+	}
+	if len(tc.cookies) > 0 {
+		fmt.Fprintf(b, "WithCookies(%s).\n", stringMap(tc.cookies))
+	}
+
+	if obj, ok := tc.json.(map[string]interface{}); ok {
+		fmt.Fprintf(b, "SendJSON(ujihttp.JSON%s).\n", literal(obj)[len("map[string]interface{}"):])
+	} else if tc.json != nil {
+		b.WriteString("// TODO: the request body example is not a JSON object\n")
+	}
+	if len(tc.form) > 0 && tc.urlEncoded {
+		fmt.Fprintf(b, "SendURLEncoded(%s).\n", stringMap(tc.form))
+	} else if len(tc.form) > 0 {
+		fmt.Fprintf(b, "SendFormData(%s).\n", stringMap(tc.form))
+	}
+
+	fmt.Fprintf(b, "Run(%s, func(req *http.Request, rec *httptest.ResponseRecorder) {\n", handler)
+	fmt.Fprintf(b, "if rec.Code != %d {\n", tc.status)
+	fmt.Fprintf(b, "t.Errorf(\"expected status %%d, got %%d\", %d, rec.Code)\n", tc.status)
+	b.WriteString("}\n})\n}\n")
+}
+
+// testName builds an exported test function name from the operationId,
+// or from the method and path when the operation has none
+func testName(id, m, p string) string {
+	src := id
+	if src == "" {
+		src = strings.ToLower(m) + " " + p
+	}
+
+	name := "Test"
+	upper := true
+	for _, r := range src {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		name += string(r)
+	}
+
+	return name
+}
+
+func stringMap(m map[string]interface{}) string {
+	b := &bytes.Buffer{}
+	b.WriteString("ujihttp.H{\n")
+	for _, k := range sortedKeys(m) {
+		fmt.Fprintf(b, "%s: %s,\n", strconv.Quote(k), strconv.Quote(fmt.Sprint(m[k])))
+	}
+	b.WriteString("}")
+
+	return b.String()
+}
+
+// literal renders a JSON compatible value as a Go expression
+func literal(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(val)
+	case bool:
+		return strconv.FormatBool(val)
+	case int, int64, uint64, float64:
+		return fmt.Sprint(val)
+	case map[string]interface{}:
+		b := &bytes.Buffer{}
+		b.WriteString("map[string]interface{}{\n")
+		for _, k := range sortedKeys(val) {
+			fmt.Fprintf(b, "%s: %s,\n", strconv.Quote(k), literal(val[k]))
+		}
+		b.WriteString("}")
+		return b.String()
+	case []interface{}:
+		b := &bytes.Buffer{}
+		b.WriteString("[]interface{}{\n")
+		for _, item := range val {
+			fmt.Fprintf(b, "%s,\n", literal(item))
+		}
+		b.WriteString("}")
+		return b.String()
+	}
+
+	return strconv.Quote(fmt.Sprint(v))
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch val := m.(type) {
+	case map[string]interface{}:
+		for k := range val {
+			keys = append(keys, k)
+		}
+	case map[string]*openapi.MediaType:
+		for k := range val {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
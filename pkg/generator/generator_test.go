@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KodepandaID/ujihttp/pkg/openapi"
+)
+
+func TestGenerate(t *testing.T) {
+	s, e := openapi.Load("../openapi/testdata/api.yaml")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	src, e := Generate(s, Options{Package: "api", Source: "api.yaml"})
+	if e != nil {
+		t.Fatal(e)
+	}
+	out := string(src)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"header", "// Generated by ujihttp gen tests from api.yaml."},
+		{"operation id", "func TestListUsers(t *testing.T) {"},
+		{"method and path", "func TestDeleteUsersId(t *testing.T) {"},
+		{"query", `GET("/users?limit=1")`},
+		{"path parameter", `DELETE("/users/7")`},
+		{"header parameter", `"X-Tenant": "acme"`},
+		{"JSON body", "SendJSON(ujihttp.JSON{"},
+		{"url-encoded body", `SendURLEncoded(ujihttp.H{`},
+		{"multipart body", `SendFormData(ujihttp.H{`},
+		{"status", "if rec.Code != 201 {"},
+		{"default status", "if rec.Code != 204 {"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("output does not contain %q:\n%s", tt.want, out)
+			}
+		})
+	}
+}
+
+func TestGenerateCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles the generated tests with go vet")
+	}
+	goBin, e := exec.LookPath("go")
+	if e != nil {
+		t.Skip("go command not found")
+	}
+
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"fixture", "../openapi/testdata/api.yaml"},
+		{"no operations", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &openapi.Spec{OpenAPI: "3.0.0"}
+			if tt.spec != "" {
+				if s, e = openapi.Load(tt.spec); e != nil {
+					t.Fatal(e)
+				}
+			}
+
+			src, e := Generate(s, Options{Package: "api"})
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			// the package is created in the module to resolve the ujihttp import
+			dir, e := os.MkdirTemp(".", "gen")
+			if e != nil {
+				t.Fatal(e)
+			}
+			defer os.RemoveAll(dir)
+
+			handler := "package api\n\nimport \"net/http\"\n\nfunc Handler() http.Handler { return http.NotFoundHandler() }\n"
+			os.WriteFile(filepath.Join(dir, "handler.go"), []byte(handler), 0644)
+			os.WriteFile(filepath.Join(dir, "api_test.go"), src, 0644)
+
+			cmd := exec.Command(goBin, "vet", "./"+filepath.Base(dir))
+			if out, e := cmd.CombinedOutput(); e != nil {
+				t.Errorf("generated tests do not compile: %v\n%s\n%s", e, out, src)
+			}
+		})
+	}
+}
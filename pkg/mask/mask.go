@@ -0,0 +1,249 @@
+package mask
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Policy decides which values are hidden before they are shown or exported
+type Policy struct {
+	// Headers are header names whose values are hidden, case-insensitive.
+	// Listing Cookie or Set-Cookie hides every cookie value.
+	Headers []string
+	// Fields are JSON, form, query and cookie names whose values are hidden, case-insensitive
+	Fields []string
+	// Patterns are applied on any shown text, only the submatches are hidden
+	// when the pattern has groups, otherwise the whole match is hidden
+	Patterns []*regexp.Regexp
+	// Replacement is written instead of a hidden value
+	Replacement string
+}
+
+// Default policy hiding common credentials
+var Default = &Policy{
+	Headers: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token"},
+	Fields: []string{"password", "passwd", "secret", "token", "access_token", "refresh_token",
+		"api_key", "apikey", "client_secret"},
+	Replacement: "***",
+}
+
+// None policy shows every value as is
+var None = &Policy{}
+
+func (p *Policy) replacement() string {
+	if p.Replacement == "" {
+		return "***"
+	}
+
+	return p.Replacement
+}
+
+func (p *Policy) isHeader(name string) bool {
+	for _, h := range p.Headers {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Policy) isField(name string) bool {
+	for _, f := range p.Fields {
+		if strings.EqualFold(f, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Header returns a copy of h with the hidden header values replaced
+func (p *Policy) Header(h http.Header) http.Header {
+	out := http.Header{}
+	for k, vals := range h {
+		for _, v := range vals {
+			if p.isHeader(k) {
+				v = p.replacement()
+			} else {
+				v = p.String(v)
+			}
+			out[k] = append(out[k], v)
+		}
+	}
+
+	return out
+}
+
+// Cookies returns a copy of the cookies with the hidden values replaced
+func (p *Policy) Cookies(cookies []*http.Cookie) []*http.Cookie {
+	all := p.isHeader("Cookie") || p.isHeader("Set-Cookie")
+
+	out := make([]*http.Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		cp := *c
+		if all || p.isField(c.Name) {
+			cp.Value = p.replacement()
+		} else {
+			cp.Value = p.String(cp.Value)
+		}
+		out = append(out, &cp)
+	}
+
+	return out
+}
+
+// URL returns the path or URL with the hidden query values replaced
+func (p *Policy) URL(s string) string {
+	u, e := url.Parse(s)
+	if e != nil || u.RawQuery == "" {
+		return p.String(s)
+	}
+
+	q := u.Query()
+	for k := range q {
+		if p.isField(k) {
+			for i := range q[k] {
+				q[k][i] = p.replacement()
+			}
+		}
+	}
+	u.RawQuery = strings.Replace(q.Encode(), url.QueryEscape(p.replacement()), p.replacement(), -1)
+
+	return p.String(u.String())
+}
+
+// Body returns a copy of the body with the hidden fields replaced,
+// JSON, url-encoded and multipart bodies are supported
+func (p *Policy) Body(ct string, b []byte) []byte {
+	if len(b) == 0 {
+		return b
+	}
+
+	mt, params, _ := mime.ParseMediaType(ct)
+	switch {
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		if out, e := p.json(b); e == nil {
+			b = out
+		}
+	case mt == "application/x-www-form-urlencoded":
+		if q, e := url.ParseQuery(string(b)); e == nil {
+			for k := range q {
+				if p.isField(k) {
+					for i := range q[k] {
+						q[k][i] = p.replacement()
+					}
+				}
+			}
+			b = []byte(q.Encode())
+		}
+	case mt == "multipart/form-data":
+		if out, e := p.multipart(b, params["boundary"]); e == nil {
+			b = out
+		}
+	}
+
+	return []byte(p.String(string(b)))
+}
+
+// String returns s with every pattern match replaced
+func (p *Policy) String(s string) string {
+	for _, re := range p.Patterns {
+		if re.NumSubexp() == 0 {
+			s = re.ReplaceAllString(s, p.replacement())
+			continue
+		}
+
+		out := &strings.Builder{}
+		last := 0
+		for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+			for g := 1; g*2 < len(m); g++ {
+				start, end := m[g*2], m[g*2+1]
+				if start < last || start < 0 {
+					continue
+				}
+				out.WriteString(s[last:start])
+				out.WriteString(p.replacement())
+				last = end
+			}
+		}
+		out.WriteString(s[last:])
+		s = out.String()
+	}
+
+	return s
+}
+
+func (p *Policy) json(b []byte) ([]byte, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if e := dec.Decode(&v); e != nil {
+		return nil, e
+	}
+
+	return json.Marshal(p.walk(v))
+}
+
+func (p *Policy) walk(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, sub := range val {
+			if p.isField(k) {
+				val[k] = p.replacement()
+				continue
+			}
+			val[k] = p.walk(sub)
+		}
+	case []interface{}:
+		for i, sub := range val {
+			val[i] = p.walk(sub)
+		}
+	}
+
+	return v
+}
+
+func (p *Policy) multipart(b []byte, boundary string) ([]byte, error) {
+	out := &bytes.Buffer{}
+	w := multipart.NewWriter(out)
+	if e := w.SetBoundary(boundary); e != nil {
+		return nil, e
+	}
+
+	r := multipart.NewReader(bytes.NewReader(b), boundary)
+	for {
+		part, e := r.NextPart()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, e
+		}
+
+		data, e := ioutil.ReadAll(part)
+		if e != nil {
+			return nil, e
+		}
+		if part.FileName() == "" && p.isField(part.FormName()) {
+			data = []byte(p.replacement())
+		}
+
+		pw, e := w.CreatePart(part.Header)
+		if e != nil {
+			return nil, e
+		}
+		pw.Write(data)
+	}
+	w.Close()
+
+	return out.Bytes(), nil
+}
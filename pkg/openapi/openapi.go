@@ -0,0 +1,358 @@
+package openapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Methods that can be declared on a path item, in the order operations are generated
+var Methods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// Spec is the subset of an OpenAPI 3 document used to generate tests
+type Spec struct {
+	OpenAPI    string               `yaml:"openapi"`
+	Paths      map[string]*PathItem `yaml:"paths"`
+	Components struct {
+		Schemas       map[string]*Schema      `yaml:"schemas"`
+		Parameters    map[string]*Parameter   `yaml:"parameters"`
+		RequestBodies map[string]*RequestBody `yaml:"requestBodies"`
+	} `yaml:"components"`
+}
+
+// PathItem is a set of operations available on a single path
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters"`
+	Get        *Operation   `yaml:"get"`
+	Post       *Operation   `yaml:"post"`
+	Put        *Operation   `yaml:"put"`
+	Patch      *Operation   `yaml:"patch"`
+	Delete     *Operation   `yaml:"delete"`
+	Head       *Operation   `yaml:"head"`
+	Options    *Operation   `yaml:"options"`
+}
+
+// Operation is a single API operation on a path
+type Operation struct {
+	OperationID string                 `yaml:"operationId"`
+	Summary     string                 `yaml:"summary"`
+	Parameters  []*Parameter           `yaml:"parameters"`
+	RequestBody *RequestBody           `yaml:"requestBody"`
+	Responses   map[string]interface{} `yaml:"responses"`
+}
+
+// Parameter is a path, query, header or cookie parameter
+type Parameter struct {
+	Ref      string      `yaml:"$ref"`
+	Name     string      `yaml:"name"`
+	In       string      `yaml:"in"`
+	Required bool        `yaml:"required"`
+	Example  interface{} `yaml:"example"`
+	Schema   *Schema     `yaml:"schema"`
+}
+
+// RequestBody is the body of an operation, keyed by media type
+type RequestBody struct {
+	Ref      string                `yaml:"$ref"`
+	Required bool                  `yaml:"required"`
+	Content  map[string]*MediaType `yaml:"content"`
+}
+
+// MediaType holds the schema and examples of a single media type
+type MediaType struct {
+	Schema   *Schema             `yaml:"schema"`
+	Example  interface{}         `yaml:"example"`
+	Examples map[string]*Example `yaml:"examples"`
+}
+
+// Example is a named example value
+type Example struct {
+	Value interface{} `yaml:"value"`
+}
+
+// Schema is the subset of a JSON schema used to build example values
+type Schema struct {
+	Ref        string             `yaml:"$ref"`
+	Type       string             `yaml:"type"`
+	Format     string             `yaml:"format"`
+	Example    interface{}        `yaml:"example"`
+	Default    interface{}        `yaml:"default"`
+	Enum       []interface{}      `yaml:"enum"`
+	Properties map[string]*Schema `yaml:"properties"`
+	Items      *Schema            `yaml:"items"`
+	AllOf      []*Schema          `yaml:"allOf"`
+	OneOf      []*Schema          `yaml:"oneOf"`
+	AnyOf      []*Schema          `yaml:"anyOf"`
+}
+
+// Load to read an OpenAPI 3 spec from a YAML or JSON file
+func Load(path string) (*Spec, error) {
+	b, e := ioutil.ReadFile(path)
+	if e != nil {
+		return nil, e
+	}
+
+	return Parse(b)
+}
+
+// Parse to decode an OpenAPI 3 spec, JSON is parsed as YAML
+func Parse(b []byte) (*Spec, error) {
+	s := &Spec{}
+	if e := yaml.Unmarshal(b, s); e != nil {
+		return nil, e
+	}
+	if !strings.HasPrefix(s.OpenAPI, "3.") {
+		return nil, fmt.Errorf("openapi: unsupported spec version %q", s.OpenAPI)
+	}
+
+	return s, nil
+}
+
+// Operations returns the operations declared on a path item keyed by method
+func (p *PathItem) Operations() map[string]*Operation {
+	ops := map[string]*Operation{}
+	for m, op := range map[string]*Operation{
+		"GET": p.Get, "POST": p.Post, "PUT": p.Put, "PATCH": p.Patch,
+		"DELETE": p.Delete, "HEAD": p.Head, "OPTIONS": p.Options,
+	} {
+		if op != nil {
+			ops[m] = op
+		}
+	}
+
+	return ops
+}
+
+// SortedPaths returns the spec paths in a stable order
+func (s *Spec) SortedPaths() []string {
+	paths := make([]string, 0, len(s.Paths))
+	for p := range s.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// Parameters returns the resolved path-level and operation-level parameters,
+// operation parameters override path parameters with the same name and location
+func (s *Spec) Parameters(p *PathItem, op *Operation) []*Parameter {
+	params := []*Parameter{}
+	index := map[string]int{}
+
+	for _, list := range [][]*Parameter{p.Parameters, op.Parameters} {
+		for _, param := range list {
+			param = s.resolveParameter(param)
+			if param == nil {
+				continue
+			}
+
+			key := param.In + ":" + param.Name
+			if i, ok := index[key]; ok {
+				params[i] = param
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, param)
+		}
+	}
+
+	return params
+}
+
+// RequestBody returns the resolved request body of an operation
+func (s *Spec) RequestBody(op *Operation) *RequestBody {
+	rb := op.RequestBody
+	if rb != nil && rb.Ref != "" {
+		rb = s.Components.RequestBodies[refName(rb.Ref)]
+	}
+
+	return rb
+}
+
+// ExpectedStatus returns the lowest 2xx status declared on an operation,
+// or 200 when the operation only declares a default response
+func (op *Operation) ExpectedStatus() int {
+	codes := []int{}
+	for k := range op.Responses {
+		var code int
+		if _, e := fmt.Sscanf(strings.ToUpper(k), "%d", &code); e == nil && code >= 200 && code < 300 {
+			codes = append(codes, code)
+		}
+		if strings.ToUpper(k) == "2XX" {
+			codes = append(codes, 200)
+		}
+	}
+	if len(codes) == 0 {
+		return 200
+	}
+	sort.Ints(codes)
+
+	return codes[0]
+}
+
+// ParameterExample returns an example value for a parameter
+func (s *Spec) ParameterExample(p *Parameter) interface{} {
+	if p.Example != nil {
+		return p.Example
+	}
+	if p.Schema != nil {
+		return s.SchemaExample(p.Schema)
+	}
+
+	return "test"
+}
+
+// MediaExample returns an example value for a media type,
+// taken from example, the first named example, or its schema
+func (s *Spec) MediaExample(mt *MediaType) interface{} {
+	if mt.Example != nil {
+		return normalize(mt.Example)
+	}
+	if len(mt.Examples) > 0 {
+		names := make([]string, 0, len(mt.Examples))
+		for n := range mt.Examples {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		if ex := mt.Examples[names[0]]; ex != nil && ex.Value != nil {
+			return normalize(ex.Value)
+		}
+	}
+	if mt.Schema != nil {
+		return s.SchemaExample(mt.Schema)
+	}
+
+	return nil
+}
+
+// SchemaExample builds an example value from a schema
+func (s *Spec) SchemaExample(sc *Schema) interface{} {
+	return s.schemaExample(sc, map[string]bool{})
+}
+
+func (s *Spec) schemaExample(sc *Schema, seen map[string]bool) interface{} {
+	if sc == nil {
+		return nil
+	}
+	if sc.Ref != "" {
+		name := refName(sc.Ref)
+		if seen[name] {
+			return nil
+		}
+		seen[name] = true
+		defer delete(seen, name)
+
+		return s.schemaExample(s.Components.Schemas[name], seen)
+	}
+
+	if sc.Example != nil {
+		return normalize(sc.Example)
+	}
+	if sc.Default != nil {
+		return normalize(sc.Default)
+	}
+	if len(sc.Enum) > 0 {
+		return normalize(sc.Enum[0])
+	}
+
+	if len(sc.AllOf) > 0 {
+		obj := map[string]interface{}{}
+		for _, sub := range sc.AllOf {
+			if m, ok := s.schemaExample(sub, seen).(map[string]interface{}); ok {
+				for k, v := range m {
+					obj[k] = v
+				}
+			}
+		}
+		return obj
+	}
+	if len(sc.OneOf) > 0 {
+		return s.schemaExample(sc.OneOf[0], seen)
+	}
+	if len(sc.AnyOf) > 0 {
+		return s.schemaExample(sc.AnyOf[0], seen)
+	}
+
+	t := sc.Type
+	if t == "" && len(sc.Properties) > 0 {
+		t = "object"
+	}
+
+	switch t {
+	case "object":
+		obj := map[string]interface{}{}
+		for name, prop := range sc.Properties {
+			if v := s.schemaExample(prop, seen); v != nil {
+				obj[name] = v
+			}
+		}
+		return obj
+	case "array":
+		if v := s.schemaExample(sc.Items, seen); v != nil {
+			return []interface{}{v}
+		}
+		return []interface{}{}
+	case "integer":
+		return 1
+	case "number":
+		return 1.5
+	case "boolean":
+		return true
+	}
+
+	switch sc.Format {
+	case "email":
+		return "test@example.com"
+	case "date":
+		return "2020-01-01"
+	case "date-time":
+		return "2020-01-01T00:00:00Z"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	}
+
+	return "test"
+}
+
+func (s *Spec) resolveParameter(p *Parameter) *Parameter {
+	if p != nil && p.Ref != "" {
+		return s.Components.Parameters[refName(p.Ref)]
+	}
+
+	return p
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// normalize converts YAML decoded values into JSON compatible values
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, sub := range val {
+			val[k] = normalize(sub)
+		}
+		return val
+	case map[interface{}]interface{}:
+		obj := map[string]interface{}{}
+		for k, sub := range val {
+			obj[fmt.Sprint(k)] = normalize(sub)
+		}
+		return obj
+	case []interface{}:
+		for i, sub := range val {
+			val[i] = normalize(sub)
+		}
+		return val
+	}
+
+	return v
+}
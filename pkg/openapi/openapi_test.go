@@ -0,0 +1,152 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func loadFixture(t *testing.T) *Spec {
+	t.Helper()
+
+	s, e := Load("testdata/api.yaml")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	return s
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr bool
+	}{
+		{"openapi 3", "openapi: 3.0.0\npaths: {}", false},
+		{"openapi 3.1 as JSON", `{"openapi": "3.1.0", "paths": {}}`, false},
+		{"swagger 2", "swagger: \"2.0\"\npaths: {}", true},
+		{"invalid YAML", "openapi: [3", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, e := Parse([]byte(tt.src))
+			if (e != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", e, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpectedStatus(t *testing.T) {
+	s := loadFixture(t)
+
+	tests := []struct {
+		path   string
+		method string
+		want   int
+	}{
+		{"/users", "GET", 200},
+		{"/users", "POST", 201},
+		{"/users/{id}", "DELETE", 200},
+		{"/login", "POST", 200},
+		{"/avatars", "PUT", 204},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			op := s.Paths[tt.path].Operations()[tt.method]
+			if op == nil {
+				t.Fatalf("no %s operation on %s", tt.method, tt.path)
+			}
+			if got := op.ExpectedStatus(); got != tt.want {
+				t.Errorf("ExpectedStatus() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParameters(t *testing.T) {
+	s := loadFixture(t)
+
+	tests := []struct {
+		path   string
+		method string
+		want   []string
+	}{
+		{"/users", "GET", []string{"query:limit", "header:X-Tenant"}},
+		{"/users/{id}", "DELETE", []string{"path:id"}},
+		{"/users", "POST", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			item := s.Paths[tt.path]
+			got := []string{}
+			for _, p := range s.Parameters(item, item.Operations()[tt.method]) {
+				got = append(got, p.In+":"+p.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parameters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaExample(t *testing.T) {
+	s := loadFixture(t)
+
+	tests := []struct {
+		name   string
+		schema *Schema
+		want   interface{}
+	}{
+		{"integer", &Schema{Type: "integer"}, 1},
+		{"number", &Schema{Type: "number"}, 1.5},
+		{"boolean", &Schema{Type: "boolean"}, true},
+		{"email", &Schema{Type: "string", Format: "email"}, "test@example.com"},
+		{"example wins", &Schema{Type: "integer", Example: 42}, 42},
+		{"enum", &Schema{Type: "string", Enum: []interface{}{"a", "b"}}, "a"},
+		{"array", &Schema{Type: "array", Items: &Schema{Type: "boolean"}}, []interface{}{true}},
+		{"recursive ref with allOf", &Schema{Ref: "#/components/schemas/User"}, map[string]interface{}{
+			"name": "test",
+			"age":  1,
+			"role": "admin",
+			"tags": []interface{}{"test"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.SchemaExample(tt.schema); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SchemaExample() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMediaExample(t *testing.T) {
+	s := loadFixture(t)
+
+	tests := []struct {
+		path   string
+		method string
+		ct     string
+		want   interface{}
+	}{
+		{"/login", "POST", "application/x-www-form-urlencoded", map[string]interface{}{
+			"user":     "test@example.com",
+			"password": "secret",
+		}},
+		{"/avatars", "PUT", "multipart/form-data", map[string]interface{}{"name": "avatar"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rb := s.RequestBody(s.Paths[tt.path].Operations()[tt.method])
+			if got := s.MediaExample(rb.Content[tt.ct]); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MediaExample() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package ujihttp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SecurityProfile is the set of security headers expected on a response
+type SecurityProfile struct {
+	// HSTSMaxAge is the minimum Strict-Transport-Security max-age in seconds, 0 skips the check
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	// CSP requires a Content-Security-Policy without unsafe script sources
+	CSP bool
+	// NoSniff requires X-Content-Type-Options: nosniff
+	NoSniff bool
+	// FrameOptions requires X-Frame-Options DENY or SAMEORIGIN, or a CSP frame-ancestors directive
+	FrameOptions bool
+	// ReferrerPolicies are the accepted Referrer-Policy values, empty skips the check
+	ReferrerPolicies []string
+	// SecureCookies requires the Secure, HttpOnly and SameSite flags on every cookie
+	SecureCookies bool
+}
+
+// SecurityFinding is a missing or weak security header
+type SecurityFinding struct {
+	Header string
+	// Severity is "missing" or "weak"
+	Severity string
+	Problem  string
+}
+
+// DefaultSecurityProfile expects every audited header
+var DefaultSecurityProfile = SecurityProfile{
+	HSTSMaxAge:       15552000,
+	CSP:              true,
+	NoSniff:          true,
+	FrameOptions:     true,
+	ReferrerPolicies: []string{"no-referrer", "same-origin", "strict-origin", "strict-origin-when-cross-origin"},
+	SecureCookies:    true,
+}
+
+// APISecurityProfile expects the headers relevant to JSON APIs, without CSP and frame options
+var APISecurityProfile = SecurityProfile{
+	HSTSMaxAge:    15552000,
+	NoSniff:       true,
+	SecureCookies: true,
+}
+
+// ExpectSecureHeaders to audit the response security headers with the profile,
+// DefaultSecurityProfile is used when no profile is given
+func (rc *ReqConf) ExpectSecureHeaders(p ...SecurityProfile) *ReqConf {
+	profile := DefaultSecurityProfile
+	if len(p) > 0 {
+		profile = p[0]
+	}
+
+	return rc.expect(func(x *exchange) error {
+		findings := AuditSecurityHeaders(x.rec.Header(), profile)
+		if len(findings) == 0 {
+			return nil
+		}
+
+		return fmt.Errorf("insecure headers:\n%s", SecurityReport(findings))
+	})
+}
+
+// AuditSecurityHeaders returns the security headers of h missing or weak for the profile
+func AuditSecurityHeaders(h http.Header, p SecurityProfile) []SecurityFinding {
+	findings := []SecurityFinding{}
+	add := func(header, severity, format string, args ...interface{}) {
+		findings = append(findings, SecurityFinding{Header: header, Severity: severity, Problem: fmt.Sprintf(format, args...)})
+	}
+
+	if p.HSTSMaxAge > 0 {
+		hsts := h.Get("Strict-Transport-Security")
+		d := directives(hsts, ";")
+		if hsts == "" {
+			add("Strict-Transport-Security", "missing", "not set")
+		} else if age, e := strconv.Atoi(d["max-age"]); e != nil || age < p.HSTSMaxAge {
+			add("Strict-Transport-Security", "weak", "max-age %q is below %d", d["max-age"], p.HSTSMaxAge)
+		} else if _, ok := d["includesubdomains"]; p.HSTSIncludeSubdomains && !ok {
+			add("Strict-Transport-Security", "weak", "includeSubDomains not set")
+		}
+	}
+
+	csp := h.Get("Content-Security-Policy")
+	cspDirectives := directives(csp, ";")
+	if p.CSP {
+		if csp == "" {
+			add("Content-Security-Policy", "missing", "not set")
+		}
+		for _, name := range []string{"default-src", "script-src"} {
+			src := " " + cspDirectives[name] + " "
+			for _, unsafe := range []string{"'unsafe-inline'", "'unsafe-eval'", " * "} {
+				if strings.Contains(src, unsafe) {
+					add("Content-Security-Policy", "weak", "%s allows %s", name, strings.TrimSpace(unsafe))
+				}
+			}
+		}
+	}
+
+	if p.NoSniff && !strings.EqualFold(h.Get("X-Content-Type-Options"), "nosniff") {
+		if h.Get("X-Content-Type-Options") == "" {
+			add("X-Content-Type-Options", "missing", "not set")
+		} else {
+			add("X-Content-Type-Options", "weak", "%q is not nosniff", h.Get("X-Content-Type-Options"))
+		}
+	}
+
+	if p.FrameOptions {
+		xfo := strings.ToUpper(h.Get("X-Frame-Options"))
+		_, ancestors := cspDirectives["frame-ancestors"]
+		if xfo == "" && !ancestors {
+			add("X-Frame-Options", "missing", "neither X-Frame-Options nor CSP frame-ancestors is set")
+		} else if xfo != "" && xfo != "DENY" && xfo != "SAMEORIGIN" && !ancestors {
+			add("X-Frame-Options", "weak", "%q is not DENY or SAMEORIGIN", h.Get("X-Frame-Options"))
+		}
+	}
+
+	if len(p.ReferrerPolicies) > 0 {
+		rp := h.Get("Referrer-Policy")
+		if rp == "" {
+			add("Referrer-Policy", "missing", "not set")
+		} else {
+			// browsers use the last policy they understand
+			values := strings.Split(rp, ",")
+			last := strings.ToLower(strings.TrimSpace(values[len(values)-1]))
+			if !contains(p.ReferrerPolicies, last) {
+				add("Referrer-Policy", "weak", "%q is not one of %s", last, strings.Join(p.ReferrerPolicies, ", "))
+			}
+		}
+	}
+
+	if p.SecureCookies {
+		for _, c := range (&http.Response{Header: h}).Cookies() {
+			missing := []string{}
+			if !c.Secure {
+				missing = append(missing, "Secure")
+			}
+			if !c.HttpOnly {
+				missing = append(missing, "HttpOnly")
+			}
+			if c.SameSite != http.SameSiteLaxMode && c.SameSite != http.SameSiteStrictMode {
+				missing = append(missing, "SameSite=Lax or Strict")
+			}
+			if len(missing) > 0 {
+				add("Set-Cookie", "weak", "cookie %q without %s", c.Name, strings.Join(missing, ", "))
+			}
+		}
+	}
+
+	return findings
+}
+
+// SecurityReport to format the findings as a readable report
+func SecurityReport(findings []SecurityFinding) string {
+	width := 0
+	for _, f := range findings {
+		if len(f.Header) > width {
+			width = len(f.Header)
+		}
+	}
+
+	lines := make([]string, 0, len(findings))
+	for _, f := range findings {
+		lines = append(lines, fmt.Sprintf("  %-7s  %-*s  %s", f.Severity, width, f.Header, f.Problem))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// directives parses a header made of name=value or name value directives, names are lowercased
+func directives(h, sep string) map[string]string {
+	d := map[string]string{}
+	for _, part := range strings.Split(h, sep) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		i := strings.IndexAny(part, "= ")
+		if i < 0 {
+			d[strings.ToLower(part)] = ""
+			continue
+		}
+		d[strings.ToLower(part[:i])] = strings.Trim(strings.TrimSpace(part[i+1:]), `"`)
+	}
+
+	return d
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,254 @@
+package ujihttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/url"
+	"time"
+
+	"github.com/KodepandaID/ujihttp/pkg/certs"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// ServerOptions is the server started to send a request over the network
+type ServerOptions struct {
+	// TLS serves HTTPS with a certificate trusted by the client
+	TLS bool
+	// HTTP2 serves HTTP/2 negotiated with ALPN, TLS is implied
+	HTTP2 bool
+	// H2C serves HTTP/2 without TLS, with prior knowledge on the client
+	H2C bool
+	// CA issues the server certificate trusted by the client, TLS is implied
+	CA *certs.CA
+	// Certificate is the server certificate, issued by CA for 127.0.0.1 when not set
+	Certificate *tls.Certificate
+	// ClientCA requires client certificates issued by it, see WithClientCert
+	ClientCA *certs.CA
+}
+
+// ServerResponse is a response received over the network
+type ServerResponse struct {
+	StatusCode int
+	Header     http.Header
+	// Body is decompressed like the body of Run
+	Body []byte
+	// Proto is the response protocol, e.g. "HTTP/2.0"
+	Proto string
+	// TLS is the connection state, nil without TLS
+	TLS *tls.ConnectionState
+	// Reused is true when the request used a connection of a previous request,
+	// with Repeat
+	Reused bool
+}
+
+// testServer is a handler served on the local network with a client trusting it
+type testServer struct {
+	*httptest.Server
+	client *http.Client
+}
+
+func newTestServer(h http.Handler, opts ServerOptions, rc *ReqConf) *testServer {
+	secure := opts.TLS || opts.HTTP2 || opts.CA != nil || opts.Certificate != nil || opts.ClientCA != nil
+	if opts.H2C && secure {
+		panic("ujihttp: H2C cannot be used with TLS")
+	}
+
+	if opts.H2C {
+		srv := httptest.NewServer(h2c.NewHandler(h, &http2.Server{}))
+		return &testServer{
+			Server: srv,
+			client: &http.Client{Transport: &http2.Transport{
+				AllowHTTP: true,
+				DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+					return net.Dial(network, addr)
+				},
+			}},
+		}
+	}
+
+	srv := httptest.NewUnstartedServer(h)
+	if !secure {
+		srv.Start()
+		return &testServer{Server: srv, client: srv.Client()}
+	}
+
+	srv.EnableHTTP2 = opts.HTTP2
+	if rc.tlsFailure {
+		// the expected handshake errors are not logged
+		srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	}
+	srv.TLS = &tls.Config{}
+	if opts.Certificate != nil {
+		srv.TLS.Certificates = []tls.Certificate{*opts.Certificate}
+	} else if opts.CA != nil {
+		srv.TLS.Certificates = []tls.Certificate{opts.CA.Server()}
+	}
+	if opts.ClientCA != nil {
+		srv.TLS.ClientCAs = opts.ClientCA.Pool()
+		srv.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	srv.StartTLS()
+
+	client := srv.Client()
+	if t, ok := client.Transport.(*http.Transport); ok {
+		if opts.CA != nil {
+			t.TLSClientConfig.RootCAs = opts.CA.Pool()
+		}
+		if rc.clientCert != nil {
+			t.TLSClientConfig.Certificates = []tls.Certificate{*rc.clientCert}
+		}
+	}
+
+	return &testServer{Server: srv, client: client}
+}
+
+// WithClientCert to present a client certificate on the TLS handshake of RunServer and WebSocket
+func (rc *ReqConf) WithClientCert(cert tls.Certificate) *ReqConf {
+	rc.clientCert = &cert
+
+	return rc
+}
+
+// ExpectTLSFailure to expect RunServer to fail on the TLS handshake,
+// for expired certificates or certificates issued by another CA
+func (rc *ReqConf) ExpectTLSFailure() *ReqConf {
+	rc.tlsFailure = true
+
+	return rc
+}
+
+// RunServer to start the handler on a local httptest.Server and send the request
+// with a real client, the expectations are checked on the received response.
+// With Repeat the requests are sent on the same client, Eventually cannot be used.
+// It returns nil when the request fails.
+func (rc *ReqConf) RunServer(r http.Handler, opts ...ServerOptions) *ServerResponse {
+	if rc.t != nil {
+		rc.t.Helper()
+	}
+	if rc.poll != nil {
+		panic("ujihttp: Eventually cannot be used with RunServer, use Run")
+	}
+
+	opt := ServerOptions{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	if rc.cassette != nil {
+		var done func()
+		r, done = rc.useCassette(r)
+		defer done()
+	}
+
+	srv := newTestServer(r, opt, rc)
+	defer srv.Close()
+	base, _ := url.Parse(srv.URL)
+
+	payload := rc.payload()
+	wire := rc.encode(payload)
+
+	runs := rc.repeat
+	if runs < 1 {
+		runs = 1
+	}
+
+	var req *http.Request
+	var resp *http.Response
+	var body []byte
+	reused := false
+	durations := make([]time.Duration, 0, runs)
+	for i := 0; i < runs; i++ {
+		req = rc.newRequest(wire)
+		req.URL.Scheme = base.Scheme
+		req.URL.Host = base.Host
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				reused = info.Reused
+			},
+		}))
+
+		var e error
+		start := time.Now()
+		resp, e = srv.client.Do(req)
+		if e == nil {
+			body, e = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		durations = append(durations, time.Since(start))
+
+		switch {
+		case rc.tlsFailure && e != nil && isTLSError(e):
+			return nil
+		case rc.tlsFailure && e != nil:
+			rc.errorf("expected a TLS failure, got %v", e)
+			return nil
+		case e != nil:
+			rc.fatalf("request over the network: %v", e)
+			return nil
+		}
+	}
+	if rc.tlsFailure {
+		rc.errorf("expected a TLS failure, got %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	x := &exchange{
+		handler:   r,
+		req:       req,
+		rec:       newRecorder(resp.StatusCode, resp.Header, body),
+		payload:   payload,
+		duration:  durations[len(durations)-1],
+		durations: durations,
+		client:    srv.client,
+	}
+	x.wireSize, x.decoded = decompress(x.rec)
+
+	if rc.coverage != nil {
+		rc.coverage.Record(rc.method, req.URL.Path, resp.StatusCode)
+	}
+	rc.checkExpectations(x)
+
+	if rc.debug {
+		rc.writeDebug(req, payload, x.rec, x.duration)
+		if runs > 1 {
+			rc.writeTiming(durations)
+		}
+	}
+
+	return &ServerResponse{
+		StatusCode: resp.StatusCode,
+		Header:     x.rec.Header(),
+		Body:       x.rec.Body.Bytes(),
+		Proto:      resp.Proto,
+		TLS:        resp.TLS,
+		Reused:     reused,
+	}
+}
+
+// isTLSError reports whether a request failed on the TLS handshake or certificate verification
+func isTLSError(e error) bool {
+	var (
+		header   tls.RecordHeaderError
+		verify   *tls.CertificateVerificationError
+		unknown  x509.UnknownAuthorityError
+		hostname x509.HostnameError
+		invalid  x509.CertificateInvalidError
+		op       *net.OpError
+	)
+
+	switch {
+	case errors.As(e, &header), errors.As(e, &verify), errors.As(e, &unknown),
+		errors.As(e, &hostname), errors.As(e, &invalid):
+		return true
+	}
+
+	// an alert sent by the server, e.g. a rejected client certificate
+	return errors.As(e, &op) && op.Op == "remote error"
+}
@@ -0,0 +1,377 @@
+package ujihttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamCloseTimeout is how long Close waits for the handler to return
+const streamCloseTimeout = time.Second
+
+var errStreamClosed = errors.New("ujihttp: stream closed")
+
+// Event is a Server-Sent Event
+type Event struct {
+	// ID is the last event ID of the stream
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+func (ev Event) String() string {
+	fields := []string{}
+	if ev.ID != "" {
+		fields = append(fields, "id="+ev.ID)
+	}
+	if ev.Event != "" {
+		fields = append(fields, "event="+ev.Event)
+	}
+	if ev.Retry != 0 {
+		fields = append(fields, "retry="+ev.Retry.String())
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(append(fields, fmt.Sprintf("data=%q", ev.Data)), " "))
+}
+
+// Stream is a streamed response of a handler, read as Server-Sent Events while it is flushed
+type Stream struct {
+	rc      *ReqConf
+	req     *http.Request
+	payload []byte
+	w       *streamWriter
+	pr      *io.PipeReader
+	cancel  context.CancelFunc
+	start   time.Time
+	events  chan Event
+	// done is closed when the handler returns, parsed when the parser stops
+	done   chan struct{}
+	parsed chan struct{}
+	closed chan struct{}
+	once   sync.Once
+	raw    bytes.Buffer
+	err    error
+}
+
+// streamWriter is a http.ResponseWriter writing the body on a pipe
+type streamWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	snapshot http.Header
+	code     int
+	wrote    chan struct{}
+	pw       *io.PipeWriter
+	gone     chan bool
+}
+
+func (w *streamWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *streamWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.snapshot != nil {
+		return
+	}
+	w.code = code
+	w.snapshot = w.header.Clone()
+	close(w.wrote)
+}
+
+func (w *streamWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+
+	return w.pw.Write(b)
+}
+
+// Flush is a no-op, written bytes are read by the stream right away
+func (w *streamWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
+
+// CloseNotify is closed when the stream is closed, gin streams need it
+func (w *streamWriter) CloseNotify() <-chan bool {
+	return w.gone
+}
+
+// Stream to start the handler and read its response as Server-Sent Events,
+// the handler runs until it returns or the stream is closed
+//
+//	s := ujihttp.New().SetTesting(t).GET("/events").Stream(GinEngine())
+//	defer s.Close()
+func (rc *ReqConf) Stream(r http.Handler) *Stream {
+	if rc.t != nil {
+		rc.t.Helper()
+	}
+
+	payload := rc.payload()
+	req := rc.newRequest(rc.encode(payload))
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	pr, pw := io.Pipe()
+	s := &Stream{
+		rc:      rc,
+		req:     req.WithContext(ctx),
+		payload: payload,
+		w: &streamWriter{
+			header: http.Header{},
+			wrote:  make(chan struct{}),
+			pw:     pw,
+			gone:   make(chan bool),
+		},
+		pr:     pr,
+		cancel: cancel,
+		start:  time.Now(),
+		events: make(chan Event),
+		done:   make(chan struct{}),
+		parsed: make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(s.done)
+		defer pw.Close()
+		defer func() {
+			if p := recover(); p != nil {
+				pw.CloseWithError(fmt.Errorf("handler panicked: %v", p))
+			}
+		}()
+
+		r.ServeHTTP(s.w, s.req)
+	}()
+	go s.parse()
+
+	return s
+}
+
+// Code returns the response status code, it waits for the handler to write the header
+func (s *Stream) Code() int {
+	s.waitHeader()
+
+	return s.w.code
+}
+
+// Header returns the response header, it waits for the handler to write the header
+func (s *Stream) Header() http.Header {
+	s.waitHeader()
+
+	return s.w.snapshot
+}
+
+func (s *Stream) waitHeader() {
+	select {
+	case <-s.w.wrote:
+	case <-s.done:
+		s.w.WriteHeader(http.StatusOK)
+	}
+}
+
+// Next returns the next event, the test fails when no event is sent before the timeout
+func (s *Stream) Next(timeout time.Duration) Event {
+	if s.rc.t != nil {
+		s.rc.t.Helper()
+	}
+
+	ev, e := s.next(time.After(timeout))
+	if e != nil {
+		s.rc.fatalf("%v", e)
+	}
+
+	return ev
+}
+
+// Collect returns the next n events and closes the stream,
+// the test fails when fewer events are sent before the timeout
+func (s *Stream) Collect(n int, timeout time.Duration) []Event {
+	if s.rc.t != nil {
+		s.rc.t.Helper()
+	}
+
+	deadline := time.After(timeout)
+	events := []Event{}
+	for len(events) < n {
+		ev, e := s.next(deadline)
+		if e != nil {
+			s.Close()
+			s.rc.fatalf("got %d of %d events: %v", len(events), n, e)
+			return events
+		}
+		events = append(events, ev)
+	}
+	s.Close()
+
+	return events
+}
+
+// ExpectEvents to expect the next events in order before the timeout,
+// the zero fields of the expected events are not compared
+func (s *Stream) ExpectEvents(timeout time.Duration, expected ...Event) *Stream {
+	if s.rc.t != nil {
+		s.rc.t.Helper()
+	}
+
+	deadline := time.After(timeout)
+	for i, want := range expected {
+		got, e := s.next(deadline)
+		if e != nil {
+			s.rc.errorf("expected event %d %s: %v", i, want, e)
+			return s
+		}
+		if !matchEvent(want, got) {
+			s.rc.errorf("expected event %d %s, got %s", i, want, got)
+			return s
+		}
+	}
+
+	return s
+}
+
+// Close to cancel the request context and stop reading the stream,
+// it waits for the handler to return
+func (s *Stream) Close() {
+	if s.rc.t != nil {
+		s.rc.t.Helper()
+	}
+
+	s.once.Do(func() {
+		close(s.closed)
+		close(s.w.gone)
+		s.cancel()
+		s.pr.CloseWithError(errStreamClosed)
+
+		select {
+		case <-s.done:
+		case <-time.After(streamCloseTimeout):
+			s.rc.errorf("handler did not return %s after the stream was closed", streamCloseTimeout)
+			return
+		}
+		<-s.parsed
+
+		s.w.WriteHeader(http.StatusOK)
+		if s.rc.coverage != nil {
+			s.rc.coverage.Record(s.rc.method, s.req.URL.Path, s.w.code)
+		}
+		if s.rc.debug {
+			rec := newRecorder(s.w.code, s.w.snapshot, s.raw.Bytes())
+			s.rc.writeDebug(s.req, s.payload, rec, time.Since(s.start))
+		}
+	})
+}
+
+func (s *Stream) next(deadline <-chan time.Time) (Event, error) {
+	select {
+	case ev, ok := <-s.events:
+		if ok {
+			return ev, nil
+		}
+		if s.err != nil && s.err != errStreamClosed {
+			return Event{}, fmt.Errorf("stream ended: %v", s.err)
+		}
+		return Event{}, errors.New("stream ended")
+	case <-deadline:
+		return Event{}, errors.New("timeout waiting for the event")
+	}
+}
+
+// parse reads the events of the stream, following the HTML event stream format
+func (s *Stream) parse() {
+	defer close(s.parsed)
+	defer close(s.events)
+
+	scanner := bufio.NewScanner(io.TeeReader(s.pr, &s.raw))
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	scanner.Split(scanEventLines)
+
+	id := ""
+	ev := Event{}
+	data := []string{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				ev.ID = id
+				ev.Data = strings.Join(data, "\n")
+				select {
+				case s.events <- ev:
+				case <-s.closed:
+					return
+				}
+			}
+			ev = Event{}
+			data = []string{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				id = value
+			}
+		case "retry":
+			if ms, e := strconv.Atoi(value); e == nil && ms >= 0 {
+				ev.Retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	s.err = scanner.Err()
+}
+
+// scanEventLines splits lines ending with \r\n, \n or \r
+func scanEventLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// a \r at the end of the buffer can be followed by \n
+		if i+1 == len(data) && !atEOF {
+			return 0, nil, nil
+		}
+		if i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+func matchEvent(want, got Event) bool {
+	return (want.ID == "" || want.ID == got.ID) &&
+		(want.Event == "" || want.Event == got.Event) &&
+		(want.Data == "" || want.Data == got.Data) &&
+		(want.Retry == 0 || want.Retry == got.Retry)
+}
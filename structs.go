@@ -0,0 +1,195 @@
+package ujihttp
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// structField is a field of a struct with the names used on JSON and form bodies
+type structField struct {
+	reflect.StructField
+	value reflect.Value
+	// jsonPath is nil when the field is skipped on JSON
+	jsonPath []string
+	// formName is empty when the field is skipped on forms
+	formName string
+	// nested is true for a struct field whose own fields are listed after it
+	nested bool
+}
+
+var fileHeaderType = reflect.TypeOf(&multipart.FileHeader{})
+
+// structFields lists the exported fields of a struct value, nested structs are walked.
+// Form names follow gin binding, the fields of a nested struct use their own form names.
+func structFields(v reflect.Value) []structField {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := []structField{}
+	walkStruct(v, []string{}, true, &fields)
+
+	return fields
+}
+
+func walkStruct(v reflect.Value, jsonPath []string, onJSON bool, fields *[]structField) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		fv := v.Field(i)
+		jsonName, jsonOK := tagName(f, "json")
+		formName, formOK := tagName(f, "form")
+
+		var path []string
+		if onJSON && jsonOK {
+			path = append(append([]string{}, jsonPath...), jsonName)
+		}
+
+		if isNestedStruct(f.Type) {
+			inner := fv
+			for inner.Kind() == reflect.Ptr {
+				if inner.IsNil() {
+					inner = reflect.New(inner.Type().Elem())
+				}
+				inner = inner.Elem()
+			}
+
+			// embedded structs without a json name are flattened like encoding/json does
+			childPath := path
+			if f.Anonymous && f.Tag.Get("json") == "" {
+				childPath = jsonPath
+			} else {
+				*fields = append(*fields, structField{StructField: f, value: fv, jsonPath: path, nested: true})
+			}
+			walkStruct(inner, childPath, onJSON && (jsonOK || f.Anonymous), fields)
+			continue
+		}
+
+		sf := structField{StructField: f, value: fv, jsonPath: path}
+		if formOK {
+			sf.formName = formName
+		}
+		*fields = append(*fields, sf)
+	}
+}
+
+// tagName returns the name of a field on a struct tag, false when the field is skipped
+func tagName(f reflect.StructField, key string) (string, bool) {
+	tag := f.Tag.Get(key)
+	if tag == "-" {
+		return "", false
+	}
+
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		name = f.Name
+	}
+
+	return name, true
+}
+
+func isNestedStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		if t == fileHeaderType {
+			return false
+		}
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{}) && t != fileHeaderType.Elem()
+}
+
+// formField is a single value or file of a form body
+type formField struct {
+	name  string
+	value string
+	file  *multipart.FileHeader
+}
+
+// formFields lists the form values and files of a struct value,
+// slices are repeated and nil pointers are skipped
+func formFields(v interface{}) []formField {
+	fields := []formField{}
+	for _, f := range structFields(reflect.ValueOf(v)) {
+		if f.nested || f.formName == "" {
+			continue
+		}
+
+		fv := f.value
+		if isNil(fv) {
+			continue
+		}
+		for (fv.Kind() == reflect.Ptr && fv.Type() != fileHeaderType) || fv.Kind() == reflect.Interface {
+			fv = fv.Elem()
+		}
+
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			for i := 0; i < fv.Len(); i++ {
+				if !isNil(fv.Index(i)) {
+					fields = append(fields, newFormField(f.formName, fv.Index(i)))
+				}
+			}
+			continue
+		}
+		fields = append(fields, newFormField(f.formName, fv))
+	}
+
+	return fields
+}
+
+// isNil reports whether a pointer or interface value, or any pointer it holds, is nil
+func isNil(v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+
+	return false
+}
+
+func newFormField(name string, v reflect.Value) formField {
+	if v.Type() == fileHeaderType {
+		return formField{name: name, file: v.Interface().(*multipart.FileHeader)}
+	}
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		return formField{name: name, value: t.Format(time.RFC3339)}
+	}
+	if b, ok := v.Interface().([]byte); ok {
+		return formField{name: name, value: string(b)}
+	}
+
+	return formField{name: name, value: fmt.Sprint(v.Interface())}
+}
+
+// openFile opens the content of a file header, a header built in a test
+// without content is read from the path in its Filename
+func openFile(fh *multipart.FileHeader) (io.ReadCloser, error) {
+	if fh.Size > 0 {
+		if f, e := fh.Open(); e == nil {
+			return f, nil
+		}
+	}
+
+	return os.Open(fh.Filename)
+}
@@ -0,0 +1,98 @@
+package ujihttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/KodepandaID/ujihttp/pkg/coverage"
+)
+
+// HandlerTransport is a http.RoundTripper serving the requests with a handler, without any socket
+type HandlerTransport struct {
+	handler http.Handler
+	rc      *ReqConf
+}
+
+// Transport to send the requests of a client to the handler, e.g. to test a client SDK
+//
+//	client := &http.Client{Transport: ujihttp.Transport(GinEngine()).SetDebug(true)}
+func Transport(h http.Handler) *HandlerTransport {
+	return &HandlerTransport{handler: h, rc: New()}
+}
+
+// SetDebug to write a debug row for every request
+func (ht *HandlerTransport) SetDebug(b bool) *HandlerTransport {
+	ht.rc.SetDebug(b)
+
+	return ht
+}
+
+// SetVerbose to write the full request and response of every request
+func (ht *HandlerTransport) SetVerbose(b bool) *HandlerTransport {
+	ht.rc.SetVerbose(b)
+
+	return ht
+}
+
+// SetDebugWriter to write the debug output on w instead of os.Stdout
+func (ht *HandlerTransport) SetDebugWriter(w io.Writer) *HandlerTransport {
+	ht.rc.SetDebugWriter(w)
+
+	return ht
+}
+
+// WithCoverage to record every request on the route coverage
+func (ht *HandlerTransport) WithCoverage(c *coverage.Coverage) *HandlerTransport {
+	ht.rc.WithCoverage(c)
+
+	return ht
+}
+
+// RoundTrip serves the request with the handler, a handler panic is returned as an error
+// like a connection closed by a server
+func (ht *HandlerTransport) RoundTrip(req *http.Request) (resp *http.Response, e error) {
+	payload := []byte{}
+	if req.Body != nil {
+		payload, e = io.ReadAll(req.Body)
+		req.Body.Close()
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	// the handler gets a server request, like the one read by http.Server
+	in := req.Clone(req.Context())
+	in.Body = io.NopCloser(bytes.NewReader(payload))
+	in.RequestURI = req.URL.RequestURI()
+	in.RemoteAddr = "192.0.2.1:1234"
+	if in.Host == "" {
+		in.Host = req.URL.Host
+	}
+	if in.Header.Get("User-Agent") == "" {
+		in.Header.Set("User-Agent", "UjiHTTP/"+version)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			resp, e = nil, fmt.Errorf("ujihttp: handler panicked: %v", p)
+		}
+	}()
+	x := serveRequest(ht.handler, in, payload)
+
+	rc := ht.rc.clone()
+	rc.method = req.Method
+	rc.path = req.URL.RequestURI()
+	if rc.coverage != nil {
+		rc.coverage.Record(rc.method, req.URL.Path, x.rec.Code)
+	}
+	if rc.debug {
+		rc.writeDebug(in, payload, x.rec, x.duration)
+	}
+
+	resp = x.rec.Result()
+	resp.Request = req
+
+	return resp, nil
+}
@@ -2,23 +2,32 @@ package ujihttp
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"testing"
 	"time"
 
 	"github.com/KodepandaID/ujihttp/pkg/cli"
+	"github.com/KodepandaID/ujihttp/pkg/coverage"
+	"github.com/KodepandaID/ujihttp/pkg/mask"
 )
 
 const version = "1.0.0"
 
-var body = &bytes.Buffer{}
-var writer *multipart.Writer
+// defaultBodyLimit is the number of body bytes shown on verbose debug
+const defaultBodyLimit = 4096
+
+// maskPolicy is the global policy hiding sensitive values
+var maskPolicy = mask.Default
 
 // H is a HTTP map string data
 type H map[string]string
@@ -34,18 +43,50 @@ type ReqConf struct {
 	cookies      H
 	send         H
 	sendJSONData JSON
+	sendForm     url.Values
 	sendFile     bool
 	contentType  string
 	debug        bool
+	verbose      bool
+	debugWriter  io.Writer
+	bodyLimit    int
 	assertStatus int
+	coverage     *coverage.Coverage
+	mask         *mask.Policy
+	t            testing.TB
+	strict       bool
+	expectations []expectation
+	final        []expectation
+	encoding     string
+	accept       string
+	repeat       int
+	clientCert   *tls.Certificate
+	tlsFailure   bool
+	poll         *polling
+	cassette     *cassetteConf
+	body         *bytes.Buffer
+	writer       *multipart.Writer
 }
 
 // ResponseFunc response handling func type
 type ResponseFunc func(*http.Request, *httptest.ResponseRecorder)
 
+// SetMask to set the global policy hiding sensitive values
+// on debug output and failure messages, mask.Default is used when not set
+func SetMask(p *mask.Policy) {
+	if p == nil {
+		p = mask.Default
+	}
+	maskPolicy = p
+}
+
 // New to start api test handler
 func New() *ReqConf {
-	return &ReqConf{}
+	return &ReqConf{
+		debugWriter: os.Stdout,
+		bodyLimit:   defaultBodyLimit,
+		body:        &bytes.Buffer{},
+	}
 }
 
 // SetDebug to enable debug mode
@@ -55,6 +96,60 @@ func (rc *ReqConf) SetDebug(b bool) *ReqConf {
 	return rc
 }
 
+// SetVerbose to enable debug mode dumping the full request and response
+func (rc *ReqConf) SetVerbose(b bool) *ReqConf {
+	rc.verbose = b
+	if b {
+		rc.debug = true
+	}
+
+	return rc
+}
+
+// SetDebugWriter to write the debug output on w instead of stdout
+func (rc *ReqConf) SetDebugWriter(w io.Writer) *ReqConf {
+	rc.debugWriter = w
+
+	return rc
+}
+
+// SetDebugBodyLimit to set the number of body bytes shown on verbose debug,
+// zero or less shows the whole body
+func (rc *ReqConf) SetDebugBodyLimit(n int) *ReqConf {
+	rc.bodyLimit = n
+
+	return rc
+}
+
+// SetTesting to report failures of RunInto and expectations on t
+func (rc *ReqConf) SetTesting(t testing.TB) *ReqConf {
+	rc.t = t
+
+	return rc
+}
+
+// SetStrict to fail decoding a JSON response with fields unknown to the target value
+func (rc *ReqConf) SetStrict(b bool) *ReqConf {
+	rc.strict = b
+
+	return rc
+}
+
+// WithMask to set the policy hiding sensitive values of this request,
+// overriding the global policy
+func (rc *ReqConf) WithMask(p *mask.Policy) *ReqConf {
+	rc.mask = p
+
+	return rc
+}
+
+// WithCoverage to record the request on a route coverage
+func (rc *ReqConf) WithCoverage(c *coverage.Coverage) *ReqConf {
+	rc.coverage = c
+
+	return rc
+}
+
 // GET request method
 func (rc *ReqConf) GET(p string) *ReqConf {
 	rc.method = "GET"
@@ -147,14 +242,89 @@ func (rc *ReqConf) SendFormData(h H) *ReqConf {
 	return rc
 }
 
+// SendURLEncoded to send application/x-www-form-urlencoded data
+func (rc *ReqConf) SendURLEncoded(h H) *ReqConf {
+	if rc.sendForm == nil {
+		rc.sendForm = url.Values{}
+	}
+	for key, val := range h {
+		rc.sendForm.Set(key, val)
+	}
+	rc.contentType = "application/x-www-form-urlencoded"
+
+	return rc
+}
+
+// SendStruct to send a struct using its json tags, or its form tags when the
+// content-type is set to multipart/form-data or application/x-www-form-urlencoded
+// with WithContentType. A *multipart.FileHeader field is sent as a file,
+// read from the path in its Filename when it has no content, it panics on url-encoded data.
+// A struct without any field to send is sent as {}.
+func (rc *ReqConf) SendStruct(v interface{}) *ReqConf {
+	ct := strings.ToLower(rc.contentType)
+
+	switch {
+	case strings.HasPrefix(ct, "application/x-www-form-urlencoded"):
+		if rc.sendForm == nil {
+			rc.sendForm = url.Values{}
+		}
+		for _, f := range formFields(v) {
+			if f.file != nil {
+				panic(fmt.Sprintf("ujihttp: SendStruct cannot send the file field %q as application/x-www-form-urlencoded, use multipart/form-data", f.name))
+			}
+			rc.sendForm.Add(f.name, f.value)
+		}
+	case strings.HasPrefix(ct, "multipart/form-data"):
+		if rc.writer == nil {
+			rc.writer = multipart.NewWriter(rc.body)
+		}
+
+		for _, f := range formFields(v) {
+			if f.file == nil {
+				rc.writer.WriteField(f.name, f.value)
+				continue
+			}
+
+			rc.sendFile = true
+			src, e := openFile(f.file)
+			if e != nil {
+				panic(e)
+			}
+			form, e := rc.writer.CreateFormFile(f.name, filepath.Base(f.file.Filename))
+			if e != nil {
+				panic(e)
+			}
+			io.Copy(form, src)
+			src.Close()
+		}
+
+		rc.contentType = rc.writer.FormDataContentType()
+	default:
+		b, e := json.Marshal(v)
+		if e != nil {
+			panic(e)
+		}
+
+		j := JSON{}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if e := dec.Decode(&j); e != nil {
+			panic(fmt.Sprintf("ujihttp: SendStruct needs a struct or map, got %T", v))
+		}
+		rc.SendJSON(j)
+	}
+
+	return rc
+}
+
 // SendFile (fieldName, filepath string)
 //
 // to send file from filepath
 func (rc *ReqConf) SendFile(fn, path string) *ReqConf {
 	rc.sendFile = true
 
-	if body.Len() == 0 {
-		writer = multipart.NewWriter(body)
+	if rc.writer == nil {
+		rc.writer = multipart.NewWriter(rc.body)
 	}
 
 	f, e := os.Open(path)
@@ -163,13 +333,13 @@ func (rc *ReqConf) SendFile(fn, path string) *ReqConf {
 	}
 	defer f.Close()
 
-	form, e := writer.CreateFormFile(fn, filepath.Base(path))
+	form, e := rc.writer.CreateFormFile(fn, filepath.Base(path))
 	if e != nil {
 		panic(e)
 	}
 	io.Copy(form, f)
 
-	rc.contentType = writer.FormDataContentType()
+	rc.contentType = rc.writer.FormDataContentType()
 
 	return rc
 }
@@ -180,8 +350,8 @@ func (rc *ReqConf) SendFile(fn, path string) *ReqConf {
 func (rc *ReqConf) SendMultipleFile(fn string, path []string) *ReqConf {
 	rc.sendFile = true
 
-	if body.Len() == 0 {
-		writer = multipart.NewWriter(body)
+	if rc.writer == nil {
+		rc.writer = multipart.NewWriter(rc.body)
 	}
 
 	for _, p := range path {
@@ -191,45 +361,147 @@ func (rc *ReqConf) SendMultipleFile(fn string, path []string) *ReqConf {
 		}
 		defer f.Close()
 
-		form, e := writer.CreateFormFile(fn, filepath.Base(p))
+		form, e := rc.writer.CreateFormFile(fn, filepath.Base(p))
 		if e != nil {
 			panic(e)
 		}
 		io.Copy(form, f)
 	}
 
-	rc.contentType = writer.FormDataContentType()
+	rc.contentType = rc.writer.FormDataContentType()
 
 	return rc
 }
 
 // Run to start api test
 func (rc *ReqConf) Run(r http.Handler, response ResponseFunc) {
+	if rc.t != nil {
+		rc.t.Helper()
+	}
+
+	if rc.cassette != nil {
+		var done func()
+		r, done = rc.useCassette(r)
+		defer done()
+	}
+
+	payload := rc.payload()
+	var x *exchange
+	var pollFailure error
+	if rc.poll != nil {
+		x, pollFailure = rc.servePoll(r, payload, rc.encode(payload))
+	} else if rc.repeat > 1 {
+		x = rc.serveRepeat(r, payload, rc.encode(payload))
+	} else {
+		x = rc.serve(r, payload, rc.encode(payload))
+	}
+
+	if rc.coverage != nil {
+		rc.coverage.Record(x.req.Method, x.req.URL.Path, x.rec.Code)
+	}
+
+	if response != nil {
+		response(x.req, x.rec)
+	}
+	if pollFailure != nil {
+		rc.errorf("%v", pollFailure)
+	}
+	rc.checkExpectations(x)
+
+	if rc.debug {
+		rc.writeDebug(x.req, payload, x.rec, x.duration)
+		if rc.repeat > 1 {
+			rc.writeTiming(x.durations)
+		}
+	}
+}
+
+// serve sends a new request with the wire body to the handler
+func (rc *ReqConf) serve(r http.Handler, payload, wire []byte) *exchange {
+	x := serveRequest(r, rc.newRequest(wire), payload)
+	x.wireSize, x.decoded = decompress(x.rec)
+
+	return x
+}
+
+// serveRequest serves the request on a new recorder
+func serveRequest(r http.Handler, req *http.Request, payload []byte) *exchange {
+	rec := httptest.NewRecorder()
+	startTime := time.Now()
+	r.ServeHTTP(rec, req)
+	endTime := time.Now().Sub(startTime)
+
+	return &exchange{
+		handler:   r,
+		req:       req,
+		rec:       rec,
+		payload:   payload,
+		duration:  endTime,
+		durations: []time.Duration{endTime},
+	}
+}
+
+// RunInto to start api test decoding the response body into v before calling response,
+// a decoding error fails the test set with SetTesting
+func (rc *ReqConf) RunInto(r http.Handler, v interface{}, response ResponseFunc) {
+	if rc.t != nil {
+		rc.t.Helper()
+	}
+
+	rc.Run(r, func(req *http.Request, rec *httptest.ResponseRecorder) {
+		if rc.t != nil {
+			rc.t.Helper()
+		}
+
+		ct := rec.Header().Get("Content-Type")
+		if e := decodeBody(ct, rec.Body.Bytes(), v, rc.strict); e != nil {
+			rc.fatalf("decoding response body: %v\nbody: %s", e, rc.maskPolicy().Body(ct, rec.Body.Bytes()))
+			return
+		}
+
+		if response != nil {
+			response(req, rec)
+		}
+	})
+}
+
+// payload returns the encoded request body
+func (rc *ReqConf) payload() []byte {
 	if len(rc.send) > 0 {
-		if body.Len() == 0 {
-			writer = multipart.NewWriter(body)
+		if rc.writer == nil {
+			rc.writer = multipart.NewWriter(rc.body)
 		}
 
 		for key, val := range rc.send {
-			writer.WriteField(key, val)
+			rc.writer.WriteField(key, val)
 		}
 
-		rc.contentType = writer.FormDataContentType()
+		rc.contentType = rc.writer.FormDataContentType()
 	}
 
-	if len(rc.sendJSONData) > 0 {
+	// an empty JSON object is still sent as {}
+	if rc.sendJSONData != nil {
 		js, e := json.Marshal(rc.sendJSONData)
 		if e != nil {
 			panic(e)
 		}
-		body = bytes.NewBuffer(js)
+		return js
+	}
+
+	if rc.sendForm != nil {
+		return []byte(rc.sendForm.Encode())
 	}
 
-	if body.Len() > 0 && len(rc.sendJSONData) == 0 {
-		writer.Close()
+	if rc.writer != nil {
+		rc.writer.Close()
 	}
 
-	req, _ := http.NewRequest(rc.method, rc.path, body)
+	return rc.body.Bytes()
+}
+
+// newRequest builds the configured request with the given body
+func (rc *ReqConf) newRequest(payload []byte) *http.Request {
+	req, _ := http.NewRequest(rc.method, rc.path, bytes.NewReader(payload))
 
 	if len(rc.headers) > 0 {
 		for key, val := range rc.headers {
@@ -249,25 +521,76 @@ func (rc *ReqConf) Run(r http.Handler, response ResponseFunc) {
 	if rc.contentType != "" {
 		req.Header.Set("Content-Type", rc.contentType)
 	}
+	if rc.encoding != "" {
+		req.Header.Set("Content-Encoding", rc.encoding)
+	}
+	if rc.accept != "" && req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", rc.accept)
+	}
 	req.Header.Set("User-Agent", "UjiHTTP/"+version)
 
-	rec := httptest.NewRecorder()
-	startTime := time.Now()
-	r.ServeHTTP(rec, req)
-	endTime := time.Now().Sub(startTime)
+	return req
+}
 
-	response(req, rec)
+// errorf reports a failure on the test, or panics when no test is set
+func (rc *ReqConf) errorf(format string, args ...interface{}) {
+	msg := rc.maskPolicy().String(fmt.Sprintf(format, args...))
+	if rc.t == nil {
+		panic(msg)
+	}
 
-	if rc.debug {
-		b, _ := ioutil.ReadAll(rec.Body)
-		bs := len(b)
-		cli.WriteDebug(&cli.DebugData{
-			Method:     rc.method,
-			Path:       rc.path,
-			Duration:   endTime,
-			BodySize:   bs,
-			Code:       rec.Code,
-			CodeStatus: http.StatusText(rec.Code),
-		})
+	rc.t.Helper()
+	rc.t.Errorf("%s %s: %s", rc.method, rc.maskPolicy().URL(rc.path), msg)
+}
+
+// fatalf reports a failure and stops the test, or panics when no test is set
+func (rc *ReqConf) fatalf(format string, args ...interface{}) {
+	msg := rc.maskPolicy().String(fmt.Sprintf(format, args...))
+	if rc.t == nil {
+		panic(msg)
+	}
+
+	rc.t.Helper()
+	rc.t.Fatalf("%s %s: %s", rc.method, rc.maskPolicy().URL(rc.path), msg)
+}
+
+func (rc *ReqConf) maskPolicy() *mask.Policy {
+	if rc.mask != nil {
+		return rc.mask
 	}
+
+	return maskPolicy
+}
+
+func (rc *ReqConf) writeDebug(req *http.Request, payload []byte, rec *httptest.ResponseRecorder, d time.Duration) {
+	m := rc.maskPolicy()
+	data := cli.DebugData{
+		Method:     req.Method,
+		Path:       m.URL(req.URL.RequestURI()),
+		Duration:   d,
+		BodySize:   rec.Body.Len(),
+		Code:       rec.Code,
+		CodeStatus: http.StatusText(rec.Code),
+	}
+
+	if !rc.verbose {
+		cli.WriteDebugTo(rc.debugWriter, &data)
+		return
+	}
+
+	cli.WriteVerbose(rc.debugWriter, &cli.VerboseData{
+		DebugData: data,
+		Proto:     req.Proto,
+		Request: cli.Message{
+			Header:  m.Header(req.Header),
+			Cookies: m.Cookies(req.Cookies()),
+			Body:    m.Body(req.Header.Get("Content-Type"), payload),
+		},
+		Response: cli.Message{
+			Header:  m.Header(rec.Header()),
+			Cookies: m.Cookies(rec.Result().Cookies()),
+			Body:    m.Body(rec.Header().Get("Content-Type"), rec.Body.Bytes()),
+		},
+		BodyLimit: rc.bodyLimit,
+	})
 }
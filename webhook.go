@@ -0,0 +1,264 @@
+package ujihttp
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// WebhookSink is a local receiver capturing the webhooks delivered by the handler under test
+type WebhookSink struct {
+	mu         sync.Mutex
+	server     *httptest.Server
+	rc         *ReqConf
+	code       int
+	deliveries []*Delivery
+	// arrived is closed and replaced on every delivery
+	arrived chan struct{}
+}
+
+// Delivery is a webhook received by a sink
+type Delivery struct {
+	Method string
+	// Path holds the query of the request
+	Path     string
+	Header   http.Header
+	Body     []byte
+	Received time.Time
+	// code is the status code the sink answered with
+	code int
+	rc   *ReqConf
+}
+
+// Signature is how a webhook body is signed with HMAC
+type Signature struct {
+	// Header holds the signature, e.g. "X-Hub-Signature-256"
+	Header string
+	// Prefix is written before the signature, e.g. "sha256="
+	Prefix string
+	// Hash is sha256.New when not set
+	Hash func() hash.Hash
+	// Base64 encodes the signature with base64 instead of hex
+	Base64 bool
+}
+
+// NewWebhookSink to start a webhook receiver, inject its URL in the config of the handler under test
+//
+//	sink := ujihttp.NewWebhookSink().SetTesting(t)
+//	defer sink.Close()
+//	sink.Await(1, time.Second)[0].Run(func(req *http.Request, rec *httptest.ResponseRecorder) {
+//		assert.Equal(t, "order.paid", rec.Header().Get("X-Event"))
+//	})
+func NewWebhookSink() *WebhookSink {
+	s := &WebhookSink{
+		rc:      New(),
+		code:    http.StatusOK,
+		arrived: make(chan struct{}),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+
+	return s
+}
+
+// URL returns the base URL of the sink, every path is accepted
+func (s *WebhookSink) URL() string {
+	return s.server.URL
+}
+
+// Close to stop the sink
+func (s *WebhookSink) Close() {
+	s.server.Close()
+}
+
+// SetTesting to report the failures on t instead of panicking
+func (s *WebhookSink) SetTesting(t testing.TB) *WebhookSink {
+	s.mu.Lock()
+	s.rc.SetTesting(t)
+	s.mu.Unlock()
+
+	return s
+}
+
+// SetDebug to write a debug row for every delivery
+func (s *WebhookSink) SetDebug(b bool) *WebhookSink {
+	s.mu.Lock()
+	s.rc.SetDebug(b)
+	s.mu.Unlock()
+
+	return s
+}
+
+// Reply to answer the deliveries with the status code, 200 by default,
+// e.g. 500 to test the retries of the handler
+func (s *WebhookSink) Reply(code int) *WebhookSink {
+	s.mu.Lock()
+	s.code = code
+	s.mu.Unlock()
+
+	return s
+}
+
+// Deliveries returns every delivery received by the sink
+func (s *WebhookSink) Deliveries() []*Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*Delivery{}, s.deliveries...)
+}
+
+// Await returns the first n deliveries,
+// the test fails when fewer deliveries are received before the timeout
+func (s *WebhookSink) Await(n int, timeout time.Duration) []*Delivery {
+	rc := s.conf()
+	if rc.t != nil {
+		rc.t.Helper()
+	}
+
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		got, arrived := len(s.deliveries), s.arrived
+		if got >= n {
+			deliveries := append([]*Delivery{}, s.deliveries[:n]...)
+			s.mu.Unlock()
+			return deliveries
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-arrived:
+		case <-deadline:
+			rc.method = "WEBHOOK"
+			rc.path = s.server.URL
+			rc.fatalf("got %d of %d deliveries after %s", got, n, timeout)
+			return s.Deliveries()
+		}
+	}
+}
+
+// conf returns a copy of the sink config, SetTesting and SetDebug can be called while serving
+func (s *WebhookSink) conf() *ReqConf {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rc.clone()
+}
+
+func (s *WebhookSink) serve(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	body, _ := io.ReadAll(r.Body)
+
+	rc := s.conf()
+	rc.method = r.Method
+	rc.path = r.URL.RequestURI()
+	d := &Delivery{
+		Method:   r.Method,
+		Path:     r.URL.RequestURI(),
+		Header:   r.Header.Clone(),
+		Body:     body,
+		Received: start,
+		rc:       rc,
+	}
+
+	s.mu.Lock()
+	code := s.code
+	d.code = code
+	s.deliveries = append(s.deliveries, d)
+	close(s.arrived)
+	s.arrived = make(chan struct{})
+	s.mu.Unlock()
+
+	w.WriteHeader(code)
+	if rc.debug {
+		rc.writeDebug(r, body, newRecorder(code, w.Header(), nil), time.Since(start))
+	}
+}
+
+// Run to call fn with the delivery as the request, and as a recorder holding its
+// header and body, so the assertions written for responses check the delivery
+func (d *Delivery) Run(fn ResponseFunc) *Delivery {
+	if d.rc.t != nil {
+		d.rc.t.Helper()
+	}
+
+	req, _ := http.NewRequest(d.Method, d.Path, bytes.NewReader(d.Body))
+	req.Header = d.Header.Clone()
+	fn(req, newRecorder(d.code, d.Header, d.Body))
+
+	return d
+}
+
+// RunInto to decode the delivery body into v based on its Content-Type before calling fn,
+// a decoding error fails the test. fn can be nil.
+func (d *Delivery) RunInto(v interface{}, fn ResponseFunc) *Delivery {
+	if d.rc.t != nil {
+		d.rc.t.Helper()
+	}
+
+	ct := d.Header.Get("Content-Type")
+	if e := decodeBody(ct, d.Body, v, d.rc.strict); e != nil {
+		d.rc.fatalf("decoding delivery body: %v\nbody: %s", e, d.rc.maskPolicy().Body(ct, d.Body))
+		return d
+	}
+	if fn != nil {
+		d.Run(fn)
+	}
+
+	return d
+}
+
+// ExpectSignature to expect the delivery body signed with the secret
+func (d *Delivery) ExpectSignature(secret string, sig Signature) *Delivery {
+	if d.rc.t != nil {
+		d.rc.t.Helper()
+	}
+
+	if sig.Header == "" {
+		panic("ujihttp: ExpectSignature needs a header")
+	}
+
+	got := d.Header.Get(sig.Header)
+	if got == "" {
+		d.rc.errorf("expected a signature on %s, got none", sig.Header)
+		return d
+	}
+	if !strings.HasPrefix(got, sig.Prefix) {
+		d.rc.errorf("expected a signature on %s starting with %q, got %q", sig.Header, sig.Prefix, got)
+		return d
+	}
+
+	want := sig.Prefix + sig.Sign(secret, d.Body)
+	if !hmac.Equal([]byte(got), []byte(want)) {
+		d.rc.errorf("signature on %s does not match the body", sig.Header)
+	}
+
+	return d
+}
+
+// Sign returns the encoded HMAC of the body without the prefix
+func (sig Signature) Sign(secret string, body []byte) string {
+	h := sig.Hash
+	if h == nil {
+		h = sha256.New
+	}
+
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	sum := mac.Sum(nil)
+
+	if sig.Base64 {
+		return base64.StdEncoding.EncodeToString(sum)
+	}
+
+	return hex.EncodeToString(sum)
+}
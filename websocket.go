@@ -0,0 +1,392 @@
+package ujihttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsWriteTimeout is the deadline of a single frame write
+const wsWriteTimeout = 5 * time.Second
+
+// WSConn is a WebSocket connection to a handler served on a local httptest.Server
+type WSConn struct {
+	rc       *ReqConf
+	server   *testServer
+	conn     *websocket.Conn
+	resp     *http.Response
+	messages chan wsMessage
+	pings    chan string
+	pongs    chan string
+	quit     chan struct{}
+	closed   bool
+}
+
+// wsMessage is a data frame or the error ending the connection
+type wsMessage struct {
+	kind int
+	data []byte
+	err  error
+}
+
+// WebSocket to start the handler on a local httptest.Server and upgrade a connection
+// to the request path, the headers and cookies of the request are sent on the handshake.
+// Set TLS or a CA on the options to connect with wss.
+//
+//	ws := ujihttp.New().SetTesting(t).GET("/ws").WebSocket(GinEngine())
+//	defer ws.Close()
+func (rc *ReqConf) WebSocket(r http.Handler, opts ...ServerOptions) *WSConn {
+	if rc.t != nil {
+		rc.t.Helper()
+	}
+
+	opt := ServerOptions{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.H2C || opt.HTTP2 {
+		panic("ujihttp: WebSocket is served on HTTP/1.1, HTTP2 and H2C cannot be used")
+	}
+
+	ws := &WSConn{
+		rc:       rc,
+		server:   newTestServer(r, opt, rc),
+		messages: make(chan wsMessage, 64),
+		pings:    make(chan string, 64),
+		pongs:    make(chan string, 64),
+		quit:     make(chan struct{}),
+	}
+
+	header := rc.newRequest(nil).Header
+	for _, k := range []string{"Upgrade", "Connection", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions"} {
+		header.Del(k)
+	}
+
+	dialer := *websocket.DefaultDialer
+	if t, ok := ws.server.client.Transport.(*http.Transport); ok {
+		dialer.TLSClientConfig = t.TLSClientConfig
+	}
+
+	start := time.Now()
+	conn, resp, e := dialer.Dial("ws"+strings.TrimPrefix(ws.server.URL, "http")+rc.path, header)
+	if resp != nil {
+		ws.resp = resp
+		body, _ := io.ReadAll(resp.Body)
+		if rc.coverage != nil {
+			rc.coverage.Record(rc.method, resp.Request.URL.Path, resp.StatusCode)
+		}
+		if rc.debug {
+			req, _ := http.NewRequest(rc.method, rc.path, nil)
+			req.Header = header
+			rc.writeDebug(req, nil, newRecorder(resp.StatusCode, resp.Header, body), time.Since(start))
+		}
+		if e != nil {
+			ws.server.Close()
+			rc.fatalf("websocket handshake: %v, got %d %s\nbody: %s", e, resp.StatusCode, http.StatusText(resp.StatusCode), body)
+			return ws
+		}
+	}
+	if e != nil {
+		ws.server.Close()
+		rc.fatalf("websocket handshake: %v", e)
+		return ws
+	}
+	ws.conn = conn
+
+	conn.SetPingHandler(func(data string) error {
+		select {
+		case ws.pings <- data:
+		default:
+		}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(wsWriteTimeout))
+	})
+	conn.SetPongHandler(func(data string) error {
+		select {
+		case ws.pongs <- data:
+		default:
+		}
+		return nil
+	})
+	go ws.read()
+
+	return ws
+}
+
+// Response returns the handshake response
+func (ws *WSConn) Response() *http.Response {
+	return ws.resp
+}
+
+// SendText to send a text message
+func (ws *WSConn) SendText(s string) *WSConn {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	return ws.write(websocket.TextMessage, []byte(s))
+}
+
+// SendJSON to send a JSON text message
+func (ws *WSConn) SendJSON(v interface{}) *WSConn {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	b, e := json.Marshal(v)
+	if e != nil {
+		panic(e)
+	}
+
+	return ws.write(websocket.TextMessage, b)
+}
+
+// SendBinary to send a binary message
+func (ws *WSConn) SendBinary(b []byte) *WSConn {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	return ws.write(websocket.BinaryMessage, b)
+}
+
+// Ping to send a ping, use ExpectPong to wait for the reply
+func (ws *WSConn) Ping(data string) *WSConn {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	if e := ws.conn.WriteControl(websocket.PingMessage, []byte(data), time.Now().Add(wsWriteTimeout)); e != nil {
+		ws.rc.errorf("websocket ping: %v", e)
+	}
+
+	return ws
+}
+
+// SendClose to send a close frame with the code and reason
+func (ws *WSConn) SendClose(code int, reason string) *WSConn {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	msg := websocket.FormatCloseMessage(code, reason)
+	if e := ws.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteTimeout)); e != nil {
+		ws.rc.errorf("websocket close: %v", e)
+	}
+
+	return ws
+}
+
+// Read returns the next text or binary message,
+// the test fails when no message is received before the timeout
+func (ws *WSConn) Read(timeout time.Duration) (kind int, data []byte) {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	m, e := ws.next(timeout)
+	if e != nil {
+		ws.rc.fatalf("websocket read: %v", e)
+		return 0, nil
+	}
+
+	return m.kind, m.data
+}
+
+// ExpectText to expect the next message to be the text s
+func (ws *WSConn) ExpectText(timeout time.Duration, s string) *WSConn {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	m, e := ws.next(timeout)
+	switch {
+	case e != nil:
+		ws.rc.errorf("expected text %q: %v", s, e)
+	case m.kind != websocket.TextMessage:
+		ws.rc.errorf("expected text %q, got %s message", s, messageKind(m.kind))
+	case string(m.data) != s:
+		ws.rc.errorf("expected text %q, got %q", s, m.data)
+	}
+
+	return ws
+}
+
+// ExpectJSON to expect the next message to be a JSON text equal to v
+func (ws *WSConn) ExpectJSON(timeout time.Duration, v interface{}) *WSConn {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	b, e := json.Marshal(v)
+	if e != nil {
+		panic(e)
+	}
+	var want interface{}
+	json.Unmarshal(b, &want)
+
+	m, e := ws.next(timeout)
+	if e != nil {
+		ws.rc.errorf("expected JSON %s: %v", b, e)
+		return ws
+	}
+
+	var got interface{}
+	if e := json.Unmarshal(m.data, &got); e != nil {
+		ws.rc.errorf("expected JSON %s, got %q: %v", b, m.data, e)
+		return ws
+	}
+	if !reflect.DeepEqual(want, got) {
+		ws.rc.errorf("expected JSON %s, got %s", b, m.data)
+	}
+
+	return ws
+}
+
+// ExpectBinary to expect the next message to be the binary b
+func (ws *WSConn) ExpectBinary(timeout time.Duration, b []byte) *WSConn {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	m, e := ws.next(timeout)
+	switch {
+	case e != nil:
+		ws.rc.errorf("expected binary % x: %v", b, e)
+	case m.kind != websocket.BinaryMessage:
+		ws.rc.errorf("expected binary % x, got %s message", b, messageKind(m.kind))
+	case !bytes.Equal(m.data, b):
+		ws.rc.errorf("expected binary % x, got % x", b, m.data)
+	}
+
+	return ws
+}
+
+// ExpectPong to expect a pong reply before the timeout
+func (ws *WSConn) ExpectPong(timeout time.Duration) *WSConn {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	select {
+	case <-ws.pongs:
+	case <-time.After(timeout):
+		ws.rc.errorf("expected pong before %s", timeout)
+	}
+
+	return ws
+}
+
+// ExpectPing to expect a ping from the handler before the timeout, pings are always answered
+func (ws *WSConn) ExpectPing(timeout time.Duration) *WSConn {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	select {
+	case <-ws.pings:
+	case <-time.After(timeout):
+		ws.rc.errorf("expected ping before %s", timeout)
+	}
+
+	return ws
+}
+
+// ExpectClose to expect the handler to close the connection with the code,
+// messages received before the close frame are skipped
+func (ws *WSConn) ExpectClose(timeout time.Duration, code int) *WSConn {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		_, e := ws.next(time.Until(deadline))
+		if e == nil {
+			continue
+		}
+
+		ce := &websocket.CloseError{}
+		switch {
+		case errors.As(e, &ce) && ce.Code == code:
+		case errors.As(e, &ce):
+			ws.rc.errorf("expected close %d, got close %d %q", code, ce.Code, ce.Text)
+		default:
+			ws.rc.errorf("expected close %d: %v", code, e)
+		}
+		return ws
+	}
+}
+
+// Close to close the connection with a normal closure and stop the server
+func (ws *WSConn) Close() {
+	if ws.closed {
+		return
+	}
+	ws.closed = true
+	close(ws.quit)
+
+	if ws.conn != nil {
+		msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		ws.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteTimeout))
+		ws.conn.Close()
+	}
+	ws.server.Close()
+}
+
+func (ws *WSConn) write(kind int, b []byte) *WSConn {
+	if ws.rc.t != nil {
+		ws.rc.t.Helper()
+	}
+
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if e := ws.conn.WriteMessage(kind, b); e != nil {
+		ws.rc.errorf("websocket write: %v", e)
+	}
+
+	return ws
+}
+
+// read receives the messages until the connection ends, the last message holds the error
+func (ws *WSConn) read() {
+	defer close(ws.messages)
+
+	for {
+		kind, data, e := ws.conn.ReadMessage()
+		select {
+		case ws.messages <- wsMessage{kind: kind, data: data, err: e}:
+		case <-ws.quit:
+			return
+		}
+		if e != nil {
+			return
+		}
+	}
+}
+
+func (ws *WSConn) next(timeout time.Duration) (wsMessage, error) {
+	select {
+	case m, ok := <-ws.messages:
+		if !ok {
+			return m, errors.New("connection closed")
+		}
+		return m, m.err
+	case <-time.After(timeout):
+		return wsMessage{}, fmt.Errorf("timeout after %s", timeout)
+	}
+}
+
+func messageKind(kind int) string {
+	if kind == websocket.BinaryMessage {
+		return "binary"
+	}
+
+	return "text"
+}